@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// cacheStoreAdapter exposes a ContextCacheStore as a CacheStore, binding
+// every call to a fixed context
+type cacheStoreAdapter struct {
+	store ContextCacheStore
+	ctx   context.Context
+}
+
+// NewCacheStoreAdapter adapts a ContextCacheStore to the CacheStore interface
+// for callers that have no request context, such as captcha.NewCaptchaStore.
+// Every call uses context.Background().
+func NewCacheStoreAdapter(store ContextCacheStore) CacheStore {
+	return NewCacheStoreAdapterWithContext(context.Background(), store)
+}
+
+// NewCacheStoreAdapterWithContext adapts a ContextCacheStore to the CacheStore
+// interface, every call uses ctx.
+func NewCacheStoreAdapterWithContext(ctx context.Context, store ContextCacheStore) CacheStore {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &cacheStoreAdapter{
+		store: store,
+		ctx:   ctx,
+	}
+}
+
+func (a *cacheStoreAdapter) Get(key string) (string, error) {
+	return a.store.Get(a.ctx, key)
+}
+
+func (a *cacheStoreAdapter) Set(key string, value interface{}, expire time.Duration) error {
+	return a.store.Set(a.ctx, key, value, expire)
+}
+
+func (a *cacheStoreAdapter) Add(key string, value interface{}, expire time.Duration) error {
+	return a.store.Add(a.ctx, key, value, expire)
+}
+
+func (a *cacheStoreAdapter) Replace(key string, value interface{}, expire time.Duration) error {
+	return a.store.Replace(a.ctx, key, value, expire)
+}
+
+func (a *cacheStoreAdapter) Delete(key string) error {
+	return a.store.Delete(a.ctx, key)
+}
+
+func (a *cacheStoreAdapter) Increment(key string, value int64) (int64, error) {
+	return a.store.Increment(a.ctx, key, value)
+}
+
+func (a *cacheStoreAdapter) Decrement(key string, value int64) (int64, error) {
+	return a.store.Decrement(a.ctx, key, value)
+}
+
+func (a *cacheStoreAdapter) Flush() error {
+	return a.store.Flush(a.ctx)
+}
+
+// contextCacheStoreAdapter exposes a CacheStore as a ContextCacheStore, the
+// context is ignored
+type contextCacheStoreAdapter struct {
+	store CacheStore
+}
+
+// NewContextCacheStoreAdapter adapts a legacy CacheStore to the
+// ContextCacheStore interface. The context passed to each call is ignored.
+func NewContextCacheStoreAdapter(store CacheStore) ContextCacheStore {
+	return &contextCacheStoreAdapter{store: store}
+}
+
+func (a *contextCacheStoreAdapter) Get(_ context.Context, key string) (string, error) {
+	return a.store.Get(key)
+}
+
+func (a *contextCacheStoreAdapter) Set(_ context.Context, key string, value interface{}, expire time.Duration) error {
+	return a.store.Set(key, value, expire)
+}
+
+func (a *contextCacheStoreAdapter) Add(_ context.Context, key string, value interface{}, expire time.Duration) error {
+	return a.store.Add(key, value, expire)
+}
+
+func (a *contextCacheStoreAdapter) Replace(_ context.Context, key string, value interface{}, expire time.Duration) error {
+	return a.store.Replace(key, value, expire)
+}
+
+func (a *contextCacheStoreAdapter) Delete(_ context.Context, key string) error {
+	return a.store.Delete(key)
+}
+
+func (a *contextCacheStoreAdapter) Increment(_ context.Context, key string, value int64) (int64, error) {
+	return a.store.Increment(key, value)
+}
+
+func (a *contextCacheStoreAdapter) Decrement(_ context.Context, key string, value int64) (int64, error) {
+	return a.store.Decrement(key, value)
+}
+
+func (a *contextCacheStoreAdapter) Flush(_ context.Context) error {
+	return a.store.Flush()
+}
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"context"
 	"time"
 )
 
@@ -11,29 +12,97 @@ const (
 
 // CacheStore is the interface of a cache backend
 type CacheStore interface {
-	// Get retrieves an item from the cache. Returns the item or nil
+	// Get retrieves an item from the cache. Returns the item or ErrCacheMiss
 	Get(key string) (string, error)
 
 	// Set sets an item to the cache, replacing any existing item.
 	Set(key string, value interface{}, expire time.Duration) error
 
 	// Add adds an item to the cache only if an item doesn't already exist for the given
-	// key, or if the existing item has expired. Returns an error otherwise.
+	// key, or if the existing item has expired. Returns ErrKeyExists otherwise.
 	Add(key string, value interface{}, expire time.Duration) error
 
-	// Replace sets a new value for the cache key only if it already exists. Returns an
-	// error if it does not.
+	// Replace sets a new value for the cache key only if it already exists. Returns
+	// ErrNotStored if it does not.
 	Replace(key string, value interface{}, expire time.Duration) error
 
 	// Delete removes an item from the cache. Does nothing if the key is not in the cache.
 	Delete(key string) error
 
-	// Increment increments a real number, and returns error if the value is not real
+	// Increment increments a real number, and returns error if the value is not real.
+	// Returns ErrCacheMiss if the key does not exist.
 	Increment(key string, value int64) (int64, error)
 
-	// Decrement decrements a real number, and returns error if the value is not real
+	// Decrement decrements a real number, and returns error if the value is not real.
+	// The result may be negative. Returns ErrCacheMiss if the key does not exist.
 	Decrement(key string, value int64) (int64, error)
 
 	// Flush deletes all items from the cache.
 	Flush() error
 }
+
+// ContextCacheStore is the context-first interface of a cache backend. Every
+// method takes the context of the calling request, so deadlines and trace spans
+// are never shared between concurrent callers.
+type ContextCacheStore interface {
+	// Get retrieves an item from the cache. Returns the item or ErrCacheMiss
+	Get(ctx context.Context, key string) (string, error)
+
+	// Set sets an item to the cache, replacing any existing item.
+	Set(ctx context.Context, key string, value interface{}, expire time.Duration) error
+
+	// Add adds an item to the cache only if an item doesn't already exist for the given
+	// key, or if the existing item has expired. Returns ErrKeyExists otherwise.
+	Add(ctx context.Context, key string, value interface{}, expire time.Duration) error
+
+	// Replace sets a new value for the cache key only if it already exists. Returns
+	// ErrNotStored if it does not.
+	Replace(ctx context.Context, key string, value interface{}, expire time.Duration) error
+
+	// Delete removes an item from the cache. Does nothing if the key is not in the cache.
+	Delete(ctx context.Context, key string) error
+
+	// Increment increments a real number, and returns error if the value is not real.
+	// Returns ErrCacheMiss if the key does not exist.
+	Increment(ctx context.Context, key string, value int64) (int64, error)
+
+	// Decrement decrements a real number, and returns error if the value is not real.
+	// The result may be negative. Returns ErrCacheMiss if the key does not exist.
+	Decrement(ctx context.Context, key string, value int64) (int64, error)
+
+	// Flush deletes all items from the cache.
+	Flush(ctx context.Context) error
+}
+
+// BatchCacheStore is implemented by backends that can operate on many keys
+// in a single round trip.
+type BatchCacheStore interface {
+	// GetMulti retrieves many items from the cache. Found items are returned in
+	// values, keys that are not in the cache are reported in misses.
+	GetMulti(ctx context.Context, keys []string) (values map[string]string, misses []string, err error)
+
+	// SetMulti sets many items to the cache, replacing any existing items.
+	SetMulti(ctx context.Context, items map[string]interface{}, expire time.Duration) error
+
+	// DeleteMulti removes many items from the cache. Keys that are not in the
+	// cache are ignored.
+	DeleteMulti(ctx context.Context, keys []string) error
+}
+
+// TagCacheStore is implemented by backends that can invalidate groups of
+// items by tag.
+type TagCacheStore interface {
+	// SetWithTags sets an item to the cache like Set and attaches tags to it.
+	SetWithTags(ctx context.Context, key string, value interface{}, expire time.Duration, tags ...string) error
+
+	// InvalidateTags removes every item carrying any of the given tags.
+	InvalidateTags(ctx context.Context, tags ...string) error
+}
+
+// PrefixCacheStore is implemented by backends that can remove every item whose
+// key starts with a prefix.
+type PrefixCacheStore interface {
+	// DeletePrefix removes every item whose key starts with prefix. An empty
+	// prefix removes every item of the store's namespace.
+	DeletePrefix(ctx context.Context, prefix string) error
+}
@@ -0,0 +1,209 @@
+// Package cachetest provides a conformance suite for cache.ContextCacheStore
+// implementations. A backend passes when it behaves like the contract
+// documented on cache.CacheStore:
+//
+//	func TestConformance(t *testing.T) {
+//		cachetest.Run(t, func(t *testing.T) cache.ContextCacheStore {
+//			return NewMyStore()
+//		})
+//	}
+package cachetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/loongkirin/gdk/cache"
+)
+
+// Factory returns an empty store for one test. Items set with a short
+// expiration must expire in real time.
+type Factory func(t *testing.T) cache.ContextCacheStore
+
+// expiration is the short TTL used by the expiry tests
+const expiration = 100 * time.Millisecond
+
+// Run runs the conformance suite against the stores created by factory
+func Run(t *testing.T, factory Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, ctx context.Context, store cache.ContextCacheStore)
+	}{
+		{"GetMiss", testGetMiss},
+		{"SetGet", testSetGet},
+		{"Expiration", testExpiration},
+		{"Add", testAdd},
+		{"AddAfterExpiration", testAddAfterExpiration},
+		{"Replace", testReplace},
+		{"Delete", testDelete},
+		{"Increment", testIncrement},
+		{"Decrement", testDecrement},
+		{"IncrementMiss", testIncrementMiss},
+		{"IncrementNotInteger", testIncrementNotInteger},
+		{"Flush", testFlush},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, context.Background(), factory(t))
+		})
+	}
+}
+
+func mustGet(t *testing.T, ctx context.Context, store cache.ContextCacheStore, key, want string) {
+	t.Helper()
+	got, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get(%q) error = %v, want %q", key, err, want)
+	}
+	if got != want {
+		t.Fatalf("Get(%q) = %q, want %q", key, got, want)
+	}
+}
+
+func mustMiss(t *testing.T, ctx context.Context, store cache.ContextCacheStore, key string) {
+	t.Helper()
+	if got, err := store.Get(ctx, key); !errors.Is(err, cache.ErrCacheMiss) {
+		t.Fatalf("Get(%q) = %q, %v, want ErrCacheMiss", key, got, err)
+	}
+}
+
+func mustSet(t *testing.T, ctx context.Context, store cache.ContextCacheStore, key string, value interface{}, expire time.Duration) {
+	t.Helper()
+	if err := store.Set(ctx, key, value, expire); err != nil {
+		t.Fatalf("Set(%q) error = %v", key, err)
+	}
+}
+
+func testGetMiss(t *testing.T, ctx context.Context, store cache.ContextCacheStore) {
+	mustMiss(t, ctx, store, "missing")
+}
+
+func testSetGet(t *testing.T, ctx context.Context, store cache.ContextCacheStore) {
+	mustSet(t, ctx, store, "bytes", []byte("value"), time.Minute)
+	mustGet(t, ctx, store, "bytes", "value")
+
+	mustSet(t, ctx, store, "string", "text", time.Minute)
+	mustGet(t, ctx, store, "string", "text")
+
+	mustSet(t, ctx, store, "int", 42, time.Minute)
+	mustGet(t, ctx, store, "int", "42")
+
+	mustSet(t, ctx, store, "forever", []byte("value"), cache.FOREVER)
+	mustGet(t, ctx, store, "forever", "value")
+
+	// Set replaces the existing item
+	mustSet(t, ctx, store, "bytes", []byte("other"), time.Minute)
+	mustGet(t, ctx, store, "bytes", "other")
+}
+
+func testExpiration(t *testing.T, ctx context.Context, store cache.ContextCacheStore) {
+	mustSet(t, ctx, store, "key", []byte("value"), expiration)
+	mustGet(t, ctx, store, "key", "value")
+
+	time.Sleep(2 * expiration)
+	mustMiss(t, ctx, store, "key")
+}
+
+func testAdd(t *testing.T, ctx context.Context, store cache.ContextCacheStore) {
+	if err := store.Add(ctx, "key", []byte("first"), time.Minute); err != nil {
+		t.Fatalf("Add of a new key error = %v", err)
+	}
+	if err := store.Add(ctx, "key", []byte("second"), time.Minute); !errors.Is(err, cache.ErrKeyExists) {
+		t.Fatalf("Add of an existing key error = %v, want ErrKeyExists", err)
+	}
+	mustGet(t, ctx, store, "key", "first")
+}
+
+func testAddAfterExpiration(t *testing.T, ctx context.Context, store cache.ContextCacheStore) {
+	if err := store.Add(ctx, "key", []byte("first"), expiration); err != nil {
+		t.Fatalf("Add of a new key error = %v", err)
+	}
+	time.Sleep(2 * expiration)
+	if err := store.Add(ctx, "key", []byte("second"), time.Minute); err != nil {
+		t.Fatalf("Add of an expired key error = %v", err)
+	}
+	mustGet(t, ctx, store, "key", "second")
+}
+
+func testReplace(t *testing.T, ctx context.Context, store cache.ContextCacheStore) {
+	if err := store.Replace(ctx, "key", []byte("value"), time.Minute); !errors.Is(err, cache.ErrNotStored) {
+		t.Fatalf("Replace of a missing key error = %v, want ErrNotStored", err)
+	}
+	mustMiss(t, ctx, store, "key")
+
+	mustSet(t, ctx, store, "key", []byte("first"), time.Minute)
+	if err := store.Replace(ctx, "key", []byte("second"), time.Minute); err != nil {
+		t.Fatalf("Replace of an existing key error = %v", err)
+	}
+	mustGet(t, ctx, store, "key", "second")
+}
+
+func testDelete(t *testing.T, ctx context.Context, store cache.ContextCacheStore) {
+	mustSet(t, ctx, store, "key", []byte("value"), time.Minute)
+	if err := store.Delete(ctx, "key"); err != nil {
+		t.Fatalf("Delete of an existing key error = %v", err)
+	}
+	mustMiss(t, ctx, store, "key")
+
+	if err := store.Delete(ctx, "missing"); err != nil {
+		t.Fatalf("Delete of a missing key error = %v, want nil", err)
+	}
+}
+
+func testIncrement(t *testing.T, ctx context.Context, store cache.ContextCacheStore) {
+	mustSet(t, ctx, store, "int", 10, time.Minute)
+	if got, err := store.Increment(ctx, "int", 5); err != nil || got != 15 {
+		t.Fatalf("Increment = %d, %v, want 15", got, err)
+	}
+	if got, err := store.Increment(ctx, "int", -20); err != nil || got != -5 {
+		t.Fatalf("Increment by a negative value = %d, %v, want -5", got, err)
+	}
+	mustGet(t, ctx, store, "int", "-5")
+
+	mustSet(t, ctx, store, "bytes", []byte("7"), time.Minute)
+	if got, err := store.Increment(ctx, "bytes", 1); err != nil || got != 8 {
+		t.Fatalf("Increment of a decimal value = %d, %v, want 8", got, err)
+	}
+	mustGet(t, ctx, store, "bytes", "8")
+}
+
+func testDecrement(t *testing.T, ctx context.Context, store cache.ContextCacheStore) {
+	mustSet(t, ctx, store, "int", 10, time.Minute)
+	if got, err := store.Decrement(ctx, "int", 3); err != nil || got != 7 {
+		t.Fatalf("Decrement = %d, %v, want 7", got, err)
+	}
+	if got, err := store.Decrement(ctx, "int", 10); err != nil || got != -3 {
+		t.Fatalf("Decrement below zero = %d, %v, want -3", got, err)
+	}
+	mustGet(t, ctx, store, "int", "-3")
+}
+
+func testIncrementMiss(t *testing.T, ctx context.Context, store cache.ContextCacheStore) {
+	if _, err := store.Increment(ctx, "missing", 1); !errors.Is(err, cache.ErrCacheMiss) {
+		t.Fatalf("Increment of a missing key error = %v, want ErrCacheMiss", err)
+	}
+	if _, err := store.Decrement(ctx, "missing", 1); !errors.Is(err, cache.ErrCacheMiss) {
+		t.Fatalf("Decrement of a missing key error = %v, want ErrCacheMiss", err)
+	}
+	mustMiss(t, ctx, store, "missing")
+}
+
+func testIncrementNotInteger(t *testing.T, ctx context.Context, store cache.ContextCacheStore) {
+	mustSet(t, ctx, store, "key", []byte("abc"), time.Minute)
+	if _, err := store.Increment(ctx, "key", 1); err == nil {
+		t.Fatal("Increment of a non integer value succeeded")
+	}
+	mustGet(t, ctx, store, "key", "abc")
+}
+
+func testFlush(t *testing.T, ctx context.Context, store cache.ContextCacheStore) {
+	mustSet(t, ctx, store, "a", []byte("1"), time.Minute)
+	mustSet(t, ctx, store, "b", []byte("2"), time.Minute)
+	if err := store.Flush(ctx); err != nil {
+		t.Fatalf("Flush error = %v", err)
+	}
+	mustMiss(t, ctx, store, "a")
+	mustMiss(t, ctx, store, "b")
+}
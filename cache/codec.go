@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/loongkirin/gdk/util"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	CodecJSON    = "json"
+	CodecGob     = "gob"
+	CodecMsgpack = "msgpack"
+)
+
+// Codec encodes values to bytes before they are written to a CacheStore
+// and decodes them back when they are read.
+type Codec interface {
+	// Name returns the name the codec is registered under
+	Name() string
+	// Marshal encodes value into bytes
+	Marshal(value interface{}) ([]byte, error)
+	// Unmarshal decodes data into the value pointed to by ptr
+	Unmarshal(data []byte, ptr interface{}) error
+}
+
+var codecs sync.Map
+
+func init() {
+	RegisterCodec(JSONCodec{})
+	RegisterCodec(GobCodec{})
+	RegisterCodec(MsgpackCodec{})
+}
+
+// RegisterCodec registers a codec under its name, replacing any codec
+// previously registered with the same name.
+func RegisterCodec(codec Codec) {
+	codecs.Store(codec.Name(), codec)
+}
+
+// GetCodec returns the codec registered under name.
+func GetCodec(name string) (Codec, error) {
+	if codec, ok := codecs.Load(name); ok {
+		return codec.(Codec), nil
+	}
+	return nil, fmt.Errorf("cache: codec %q not registered", name)
+}
+
+// JSONCodec encodes values with encoding/json
+type JSONCodec struct{}
+
+func (JSONCodec) Name() string {
+	return CodecJSON
+}
+
+func (JSONCodec) Marshal(value interface{}) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (JSONCodec) Unmarshal(data []byte, ptr interface{}) error {
+	return json.Unmarshal(data, ptr)
+}
+
+// GobCodec encodes values with util.Serialize, integers are stored as
+// decimal strings and everything else is gob encoded
+type GobCodec struct{}
+
+func (GobCodec) Name() string {
+	return CodecGob
+}
+
+func (GobCodec) Marshal(value interface{}) ([]byte, error) {
+	return util.Serialize(value)
+}
+
+func (GobCodec) Unmarshal(data []byte, ptr interface{}) error {
+	return util.Deserialize(data, ptr)
+}
+
+// MsgpackCodec encodes values with MessagePack
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) Name() string {
+	return CodecMsgpack
+}
+
+func (MsgpackCodec) Marshal(value interface{}) ([]byte, error) {
+	return msgpack.Marshal(value)
+}
+
+func (MsgpackCodec) Unmarshal(data []byte, ptr interface{}) error {
+	return msgpack.Unmarshal(data, ptr)
+}
@@ -0,0 +1,348 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/loongkirin/gdk/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// 定义指标
+	cacheHitsTotalDef = telemetry.MetricDefinition[float64]{
+		Name:        "cache_hits_total",
+		Description: "Total number of cache hits",
+		Unit:        "1",
+		Kind:        telemetry.KindCounter,
+	}
+
+	cacheMissesTotalDef = telemetry.MetricDefinition[float64]{
+		Name:        "cache_misses_total",
+		Description: "Total number of cache misses",
+		Unit:        "1",
+		Kind:        telemetry.KindCounter,
+	}
+
+	cacheErrorsTotalDef = telemetry.MetricDefinition[float64]{
+		Name:        "cache_errors_total",
+		Description: "Total number of failed cache operations",
+		Unit:        "1",
+		Kind:        telemetry.KindCounter,
+	}
+
+	cacheOperationDurationDef = telemetry.MetricDefinition[float64]{
+		Name:        "cache_operation_duration_seconds",
+		Description: "Cache operation duration in seconds",
+		Unit:        "s",
+		Kind:        telemetry.KindHistogram,
+	}
+)
+
+// InstrumentOptions represents configuration options for InstrumentedStore
+type InstrumentOptions struct {
+	// Name identifies the cache in metrics and spans
+	Name string
+	// PrefixFunc maps a key to the prefix used as a metric attribute, defaults
+	// to DefaultKeyPrefix. Its values must be bounded, every prefix is a series.
+	PrefixFunc func(key string) string
+	// Tracer creates the operation spans, defaults to telemetry.GetTracer("gdk/cache")
+	Tracer trace.Tracer
+}
+
+// OtherKeyPrefix is the prefix of the keys without a ":"
+const OtherKeyPrefix = "other"
+
+// DefaultKeyPrefix returns the part of key before the first ":", or
+// OtherKeyPrefix when there is none so keys never become metric attributes
+func DefaultKeyPrefix(key string) string {
+	if i := strings.Index(key, ":"); i >= 0 {
+		return key[:i]
+	}
+	return OtherKeyPrefix
+}
+
+// InstrumentedStore decorates a ContextCacheStore with hit, miss, error and
+// latency metrics per key prefix, and a span per operation. Extension
+// interfaces of the wrapped store are passed through, ErrNotSupport is
+// returned when the wrapped store does not implement them.
+type InstrumentedStore struct {
+	store        ContextCacheStore
+	dynamicMeter *telemetry.DynamicMeter[float64]
+	tracer       trace.Tracer
+	options      InstrumentOptions
+}
+
+var (
+	_ ContextCacheStore = (*InstrumentedStore)(nil)
+	_ BatchCacheStore   = (*InstrumentedStore)(nil)
+	_ TagCacheStore     = (*InstrumentedStore)(nil)
+	_ PrefixCacheStore  = (*InstrumentedStore)(nil)
+)
+
+// NewInstrumentedStore wraps store with metrics recorded through dynamicMeter
+// and tracing
+func NewInstrumentedStore(store ContextCacheStore, dynamicMeter *telemetry.DynamicMeter[float64], opts InstrumentOptions) (*InstrumentedStore, error) {
+	metrics := []telemetry.MetricDefinition[float64]{
+		cacheHitsTotalDef,
+		cacheMissesTotalDef,
+		cacheErrorsTotalDef,
+		cacheOperationDurationDef,
+	}
+	for _, def := range metrics {
+		if _, err := dynamicMeter.GetOrCreateMetric(def); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.PrefixFunc == nil {
+		opts.PrefixFunc = DefaultKeyPrefix
+	}
+	tracer := opts.Tracer
+	if tracer == nil {
+		tracer = telemetry.GetTracer("gdk/cache")
+	}
+
+	return &InstrumentedStore{
+		store:        store,
+		dynamicMeter: dynamicMeter,
+		tracer:       tracer,
+		options:      opts,
+	}, nil
+}
+
+// IsCacheMiss reports whether err means the item was not in the cache
+func IsCacheMiss(err error) bool {
+	return errors.Is(err, ErrCacheMiss) || errors.Is(err, ErrNotStored)
+}
+
+// isExpected reports whether err is an expected outcome rather than a
+// failure, like a miss or an Add of an existing key
+func isExpected(err error) bool {
+	return IsCacheMiss(err) || errors.Is(err, ErrKeyExists)
+}
+
+// observation collects the outcome of one operation
+type observation struct {
+	ctx    context.Context
+	span   trace.Span
+	op     string
+	prefix string
+	start  time.Time
+}
+
+func (s *InstrumentedStore) start(ctx context.Context, op, key string) *observation {
+	prefix := ""
+	attrs := []attribute.KeyValue{
+		attribute.String("cache.name", s.options.Name),
+		attribute.String("cache.operation", op),
+	}
+	if key != "" {
+		prefix = s.options.PrefixFunc(key)
+		attrs = append(attrs,
+			attribute.String("cache.key", key),
+			attribute.String("cache.prefix", prefix),
+		)
+	}
+	ctx, span := s.tracer.Start(ctx, "cache."+op, trace.WithAttributes(attrs...))
+	return &observation{
+		ctx:    ctx,
+		span:   span,
+		op:     op,
+		prefix: prefix,
+		start:  time.Now(),
+	}
+}
+
+// record counts n metric events for the operation's prefix
+func (s *InstrumentedStore) record(o *observation, name, prefix string, n int) {
+	if n <= 0 {
+		return
+	}
+	s.dynamicMeter.RecordMetric(o.ctx, telemetry.MetricValue[float64]{
+		Name:  name,
+		Value: float64(n),
+		Attributes: []attribute.KeyValue{
+			attribute.String("cache", s.options.Name),
+			attribute.String("operation", o.op),
+			attribute.String("prefix", prefix),
+		},
+	})
+}
+
+// finish records latency and the error of the operation and ends the span.
+// Misses and existing keys are expected outcomes and are not reported as
+// errors.
+func (s *InstrumentedStore) finish(o *observation, err error) {
+	s.dynamicMeter.RecordMetric(o.ctx, telemetry.MetricValue[float64]{
+		Name:  cacheOperationDurationDef.Name,
+		Value: time.Since(o.start).Seconds(),
+		Attributes: []attribute.KeyValue{
+			attribute.String("cache", s.options.Name),
+			attribute.String("operation", o.op),
+			attribute.String("prefix", o.prefix),
+		},
+	})
+
+	if err != nil && !isExpected(err) {
+		s.record(o, cacheErrorsTotalDef.Name, o.prefix, 1)
+		o.span.RecordError(err)
+		o.span.SetStatus(codes.Error, err.Error())
+	}
+	o.span.End()
+}
+
+func (s *InstrumentedStore) Get(ctx context.Context, key string) (string, error) {
+	o := s.start(ctx, "get", key)
+	value, err := s.store.Get(o.ctx, key)
+	switch {
+	case err == nil:
+		s.record(o, cacheHitsTotalDef.Name, o.prefix, 1)
+		o.span.SetAttributes(attribute.Bool("cache.hit", true))
+	case IsCacheMiss(err):
+		s.record(o, cacheMissesTotalDef.Name, o.prefix, 1)
+		o.span.SetAttributes(attribute.Bool("cache.hit", false))
+	}
+	s.finish(o, err)
+	return value, err
+}
+
+func (s *InstrumentedStore) Set(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	o := s.start(ctx, "set", key)
+	err := s.store.Set(o.ctx, key, value, expire)
+	s.finish(o, err)
+	return err
+}
+
+func (s *InstrumentedStore) Add(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	o := s.start(ctx, "add", key)
+	err := s.store.Add(o.ctx, key, value, expire)
+	s.finish(o, err)
+	return err
+}
+
+func (s *InstrumentedStore) Replace(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	o := s.start(ctx, "replace", key)
+	err := s.store.Replace(o.ctx, key, value, expire)
+	s.finish(o, err)
+	return err
+}
+
+func (s *InstrumentedStore) Delete(ctx context.Context, key string) error {
+	o := s.start(ctx, "delete", key)
+	err := s.store.Delete(o.ctx, key)
+	s.finish(o, err)
+	return err
+}
+
+func (s *InstrumentedStore) Increment(ctx context.Context, key string, value int64) (int64, error) {
+	o := s.start(ctx, "increment", key)
+	newValue, err := s.store.Increment(o.ctx, key, value)
+	s.finish(o, err)
+	return newValue, err
+}
+
+func (s *InstrumentedStore) Decrement(ctx context.Context, key string, value int64) (int64, error) {
+	o := s.start(ctx, "decrement", key)
+	newValue, err := s.store.Decrement(o.ctx, key, value)
+	s.finish(o, err)
+	return newValue, err
+}
+
+func (s *InstrumentedStore) Flush(ctx context.Context) error {
+	o := s.start(ctx, "flush", "")
+	err := s.store.Flush(o.ctx)
+	s.finish(o, err)
+	return err
+}
+
+func (s *InstrumentedStore) GetMulti(ctx context.Context, keys []string) (map[string]string, []string, error) {
+	batch, ok := s.store.(BatchCacheStore)
+	if !ok {
+		return nil, nil, ErrNotSupport
+	}
+	o := s.start(ctx, "get_multi", "")
+	o.span.SetAttributes(attribute.Int("cache.keys", len(keys)))
+	values, misses, err := batch.GetMulti(o.ctx, keys)
+	if err == nil {
+		hits := make(map[string]int)
+		for key := range values {
+			hits[s.options.PrefixFunc(key)]++
+		}
+		for prefix, n := range hits {
+			s.record(o, cacheHitsTotalDef.Name, prefix, n)
+		}
+		missed := make(map[string]int)
+		for _, key := range misses {
+			missed[s.options.PrefixFunc(key)]++
+		}
+		for prefix, n := range missed {
+			s.record(o, cacheMissesTotalDef.Name, prefix, n)
+		}
+		o.span.SetAttributes(attribute.Int("cache.hits", len(values)), attribute.Int("cache.misses", len(misses)))
+	}
+	s.finish(o, err)
+	return values, misses, err
+}
+
+func (s *InstrumentedStore) SetMulti(ctx context.Context, items map[string]interface{}, expire time.Duration) error {
+	batch, ok := s.store.(BatchCacheStore)
+	if !ok {
+		return ErrNotSupport
+	}
+	o := s.start(ctx, "set_multi", "")
+	o.span.SetAttributes(attribute.Int("cache.keys", len(items)))
+	err := batch.SetMulti(o.ctx, items, expire)
+	s.finish(o, err)
+	return err
+}
+
+func (s *InstrumentedStore) DeleteMulti(ctx context.Context, keys []string) error {
+	batch, ok := s.store.(BatchCacheStore)
+	if !ok {
+		return ErrNotSupport
+	}
+	o := s.start(ctx, "delete_multi", "")
+	o.span.SetAttributes(attribute.Int("cache.keys", len(keys)))
+	err := batch.DeleteMulti(o.ctx, keys)
+	s.finish(o, err)
+	return err
+}
+
+func (s *InstrumentedStore) SetWithTags(ctx context.Context, key string, value interface{}, expire time.Duration, tags ...string) error {
+	tagged, ok := s.store.(TagCacheStore)
+	if !ok {
+		return ErrNotSupport
+	}
+	o := s.start(ctx, "set_with_tags", key)
+	err := tagged.SetWithTags(o.ctx, key, value, expire, tags...)
+	s.finish(o, err)
+	return err
+}
+
+func (s *InstrumentedStore) InvalidateTags(ctx context.Context, tags ...string) error {
+	tagged, ok := s.store.(TagCacheStore)
+	if !ok {
+		return ErrNotSupport
+	}
+	o := s.start(ctx, "invalidate_tags", "")
+	o.span.SetAttributes(attribute.StringSlice("cache.tags", tags))
+	err := tagged.InvalidateTags(o.ctx, tags...)
+	s.finish(o, err)
+	return err
+}
+
+func (s *InstrumentedStore) DeletePrefix(ctx context.Context, prefix string) error {
+	prefixed, ok := s.store.(PrefixCacheStore)
+	if !ok {
+		return ErrNotSupport
+	}
+	o := s.start(ctx, "delete_prefix", prefix)
+	err := prefixed.DeletePrefix(o.ctx, prefix)
+	s.finish(o, err)
+	return err
+}
@@ -0,0 +1,69 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/loongkirin/gdk/cache"
+	"github.com/loongkirin/gdk/cache/memeorycache"
+	"github.com/loongkirin/gdk/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestInstrumentedStoreCountsHitsAndMisses(t *testing.T) {
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	dynamicMeter := telemetry.NewDynamicMeter[float64](provider.Meter("test"))
+
+	store, err := cache.NewInstrumentedStore(memeorycache.NewInMemoryStore(time.Minute), dynamicMeter, cache.InstrumentOptions{Name: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store.Set(ctx, "user:1", []byte("a"), cache.DEFAULT)
+	store.Get(ctx, "user:1")
+	store.Get(ctx, "user:2")
+	store.Get(ctx, "order:1")
+	store.GetMulti(ctx, []string{"user:1", "user:3"})
+	store.Get(ctx, "session")
+	store.Add(ctx, "user:1", []byte("b"), cache.DEFAULT)
+	store.Replace(ctx, "user:4", []byte("b"), cache.DEFAULT)
+	store.Increment(ctx, "user:5", 1)
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]float64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			sum, ok := m.Data.(metricdata.Sum[float64])
+			if !ok {
+				continue
+			}
+			for _, dp := range sum.DataPoints {
+				prefix, _ := dp.Attributes.Value(attribute.Key("prefix"))
+				got[m.Name+"/"+prefix.AsString()] += dp.Value
+			}
+		}
+	}
+
+	want := map[string]float64{
+		"cache_hits_total/user":    2,
+		"cache_misses_total/user":  2,
+		"cache_misses_total/order": 1,
+		"cache_misses_total/other": 1,
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %v, want %v", k, got[k], v)
+		}
+	}
+	if got["cache_errors_total/user"] != 0 {
+		t.Errorf("expected outcomes were counted as errors: %v", got)
+	}
+}
@@ -0,0 +1,222 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// notFoundMarker is stored in place of a value to cache a not-found result
+const notFoundMarker = "\x00gdk:cache:not_found\x00"
+
+// ErrNotFound is returned by a LoadFunc when the value does not exist in the
+// source of truth. It is cached for LoaderOptions.NegativeExpiration.
+var ErrNotFound = errors.New("cache: item not found in source")
+
+// LoadFunc loads a value from the source of truth on a cache miss
+type LoadFunc[T any] func(ctx context.Context) (T, error)
+
+// Lock is the subset of a distributed lock used by Loader to collapse
+// misses across replicas
+type Lock interface {
+	// Lock attempts to acquire the lock
+	Lock(ctx context.Context) error
+	// Unlock releases the lock if it is still held
+	Unlock(ctx context.Context) error
+}
+
+// LockFactory creates a lock for the given key
+type LockFactory func(key string) (Lock, error)
+
+// LoaderOptions represents configuration options for Loader
+type LoaderOptions struct {
+	// NegativeExpiration is how long a not-found result is cached, zero
+	// disables negative caching
+	NegativeExpiration time.Duration
+	// LockFactory enables cross-replica stampede protection when set
+	LockFactory LockFactory
+	// LockWaitTimeout is how long a replica that did not get the lock waits
+	// for the holder to fill the cache before loading by itself
+	LockWaitTimeout time.Duration
+	// LockPollInterval is the interval at which a waiting replica checks the cache
+	LockPollInterval time.Duration
+	// RefreshTimeout bounds a background refresh of GetOrRevalidate
+	RefreshTimeout time.Duration
+	// LoadTimeout bounds a load shared by concurrent callers, which does not
+	// stop when one of them gives up
+	LoadTimeout time.Duration
+}
+
+// DefaultLoaderOptions returns the default options for Loader
+func DefaultLoaderOptions() LoaderOptions {
+	return LoaderOptions{
+		LockWaitTimeout:  time.Second * 3,
+		LockPollInterval: time.Millisecond * 50,
+		RefreshTimeout:   time.Second * 10,
+		LoadTimeout:      time.Second * 10,
+	}
+}
+
+// Loader implements the cache-aside pattern on top of a ContextCacheStore.
+// Concurrent misses for the same key within one process collapse to a single
+// LoadFunc call, and optionally across replicas with a distributed lock.
+type Loader[T any] struct {
+	store   ContextCacheStore
+	codec   Codec
+	options LoaderOptions
+	group   singleflight.Group
+	// refreshing holds the keys being revalidated in the background
+	refreshing sync.Map
+}
+
+// NewLoader creates a Loader, JSON is used when codec is nil
+func NewLoader[T any](store ContextCacheStore, codec Codec, opts LoaderOptions) *Loader[T] {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+	if opts.LockWaitTimeout <= 0 {
+		opts.LockWaitTimeout = DefaultLoaderOptions().LockWaitTimeout
+	}
+	if opts.LockPollInterval <= 0 {
+		opts.LockPollInterval = DefaultLoaderOptions().LockPollInterval
+	}
+	if opts.RefreshTimeout <= 0 {
+		opts.RefreshTimeout = DefaultLoaderOptions().RefreshTimeout
+	}
+	if opts.LoadTimeout <= 0 {
+		opts.LoadTimeout = DefaultLoaderOptions().LoadTimeout
+	}
+	return &Loader[T]{
+		store:   store,
+		codec:   codec,
+		options: opts,
+	}
+}
+
+// GetOrLoad returns the cached value of key. On a miss it calls loader, stores
+// the result for ttl and returns it. ErrNotFound is returned, and cached when
+// negative caching is enabled, if the loader reports the value does not exist.
+// Store errors other than ErrCacheMiss are returned rather than loading, so
+// a cache outage does not stampede the source of truth.
+func (l *Loader[T]) GetOrLoad(ctx context.Context, key string, ttl time.Duration, loader LoadFunc[T]) (T, error) {
+	if value, hit, err := l.get(ctx, key); hit {
+		return value, err
+	}
+	return l.loadOnce(ctx, key, 0, ttl, loader)
+}
+
+// loadOnce loads key once per process, and once across replicas when a
+// LockFactory is set. Each caller stops waiting when its ctx is done while
+// the shared load goes on until LoadTimeout.
+func (l *Loader[T]) loadOnce(ctx context.Context, key string, softTTL, ttl time.Duration, loader LoadFunc[T]) (T, error) {
+	ch := l.group.DoChan(key, func() (interface{}, error) {
+		// 共享的加载不受首个调用方取消的影响
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), l.options.LoadTimeout)
+		defer cancel()
+		if l.options.LockFactory != nil {
+			return l.loadWithLock(ctx, key, softTTL, ttl, loader)
+		}
+		return l.load(ctx, key, softTTL, ttl, loader)
+	})
+
+	var zero T
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case result := <-ch:
+		if result.Err != nil {
+			return zero, result.Err
+		}
+		// 值为 nil 时（如 T 为接口类型）断言失败，返回零值而不是 panic
+		value, _ := result.Val.(T)
+		return value, nil
+	}
+}
+
+// get reads key from the store, hit is false when the caller has to load
+func (l *Loader[T]) get(ctx context.Context, key string) (value T, hit bool, err error) {
+	value, hit, _, err = l.lookup(ctx, key)
+	return value, hit, err
+}
+
+// lookup is get that also reports whether the entry is past its soft TTL
+func (l *Loader[T]) lookup(ctx context.Context, key string) (value T, hit bool, stale bool, err error) {
+	raw, err := l.store.Get(ctx, key)
+	if errors.Is(err, ErrCacheMiss) {
+		return value, false, false, nil
+	}
+	if err != nil {
+		return value, true, false, err
+	}
+	if raw == notFoundMarker {
+		return value, true, false, ErrNotFound
+	}
+	raw, stale = decodeEntry(raw)
+	if err := l.codec.Unmarshal([]byte(raw), &value); err != nil {
+		// Treat undecodable entries as a miss so they get overwritten
+		return value, false, false, nil
+	}
+	return value, true, stale, nil
+}
+
+func (l *Loader[T]) load(ctx context.Context, key string, softTTL, ttl time.Duration, loader LoadFunc[T]) (T, error) {
+	value, err := loader(ctx)
+	if errors.Is(err, ErrNotFound) {
+		if l.options.NegativeExpiration > 0 {
+			_ = l.store.Set(ctx, key, []byte(notFoundMarker), l.options.NegativeExpiration)
+		}
+		return value, ErrNotFound
+	}
+	if err != nil {
+		return value, err
+	}
+
+	data, err := l.codec.Marshal(value)
+	if err != nil {
+		return value, err
+	}
+	if softTTL > 0 {
+		data = encodeEntry(data, softTTL)
+	}
+	// The loaded value is still good even if it could not be cached
+	_ = l.store.Set(ctx, key, data, ttl)
+	return value, nil
+}
+
+func (l *Loader[T]) loadWithLock(ctx context.Context, key string, softTTL, ttl time.Duration, loader LoadFunc[T]) (T, error) {
+	lock, err := l.options.LockFactory("lock:" + key)
+	if err != nil {
+		return l.load(ctx, key, softTTL, ttl, loader)
+	}
+
+	if err := lock.Lock(ctx); err == nil {
+		defer lock.Unlock(context.WithoutCancel(ctx))
+		// Another replica may have filled the cache before we got the lock
+		if value, hit, err := l.get(ctx, key); hit {
+			return value, err
+		}
+		return l.load(ctx, key, softTTL, ttl, loader)
+	}
+
+	// Another replica is loading, wait for it to fill the cache
+	timer := time.NewTimer(l.options.LockWaitTimeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(l.options.LockPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		case <-timer.C:
+			return l.load(ctx, key, softTTL, ttl, loader)
+		case <-ticker.C:
+			if value, hit, err := l.get(ctx, key); hit {
+				return value, err
+			}
+		}
+	}
+}
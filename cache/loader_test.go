@@ -0,0 +1,129 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/loongkirin/gdk/cache"
+	"github.com/loongkirin/gdk/cache/memeorycache"
+)
+
+func TestLoaderCollapsesConcurrentMisses(t *testing.T) {
+	ctx := context.Background()
+	loader := cache.NewLoader[string](memeorycache.NewInMemoryStore(time.Minute), nil, cache.DefaultLoaderOptions())
+
+	var calls int32
+	load := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(20 * time.Millisecond)
+		return "value", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := loader.GetOrLoad(ctx, "key", time.Minute, load)
+			if err != nil || v != "value" {
+				t.Errorf("got %q, %v", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("loader called %d times, want 1", calls)
+	}
+	if v, err := loader.GetOrLoad(ctx, "key", time.Minute, load); err != nil || v != "value" || calls != 1 {
+		t.Errorf("cached read got %q, %v after %d calls", v, err, calls)
+	}
+}
+
+func TestLoaderNegativeCaching(t *testing.T) {
+	ctx := context.Background()
+	opts := cache.DefaultLoaderOptions()
+	opts.NegativeExpiration = time.Minute
+	loader := cache.NewLoader[int](memeorycache.NewInMemoryStore(time.Minute), nil, opts)
+
+	var calls int32
+	load := func(ctx context.Context) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		return 0, cache.ErrNotFound
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := loader.GetOrLoad(ctx, "missing", time.Minute, load); err != cache.ErrNotFound {
+			t.Fatalf("got %v, want ErrNotFound", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("loader called %d times, want 1", calls)
+	}
+}
+
+// failingStore fails every read like an unreachable cache
+type failingStore struct {
+	cache.ContextCacheStore
+}
+
+func (s failingStore) Get(ctx context.Context, key string) (string, error) {
+	return "", errors.New("connection refused")
+}
+
+func TestLoaderReturnsStoreErrors(t *testing.T) {
+	store := failingStore{memeorycache.NewInMemoryStore(time.Minute)}
+	loader := cache.NewLoader[string](store, nil, cache.DefaultLoaderOptions())
+
+	var calls int32
+	_, err := loader.GetOrLoad(context.Background(), "key", time.Minute, func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "value", nil
+	})
+	if err == nil || calls != 0 {
+		t.Errorf("got %v after %d loads, want the store error and no load", err, calls)
+	}
+}
+
+func TestLoaderSharedLoadSurvivesCancellation(t *testing.T) {
+	loader := cache.NewLoader[string](memeorycache.NewInMemoryStore(time.Minute), nil, cache.DefaultLoaderOptions())
+	started := make(chan struct{})
+	load := func(ctx context.Context) (string, error) {
+		close(started)
+		select {
+		case <-time.After(50 * time.Millisecond):
+			return "value", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	// 首个调用方取消后，等待同一次加载的其他调用方仍然得到结果
+	first, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := loader.GetOrLoad(first, "key", time.Minute, load)
+		firstErr <- err
+	}()
+	<-started
+	second := make(chan error, 1)
+	go func() {
+		v, err := loader.GetOrLoad(context.Background(), "key", time.Minute, load)
+		if err == nil && v != "value" {
+			err = errors.New("got " + v)
+		}
+		second <- err
+	}()
+	cancel()
+
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled caller got %v", err)
+	}
+	if err := <-second; err != nil {
+		t.Errorf("waiting caller got %v", err)
+	}
+}
@@ -3,19 +3,23 @@ package memeorycache
 import (
 	"encoding/binary"
 	"encoding/gob"
-	"errors"
 	"fmt"
 	"hash/fnv"
 	"io"
 	"os"
 	"runtime"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/loongkirin/gdk/cache"
 )
 
 var (
-	ErrKeyExists = errors.New("cache: item already exists")
-	ErrCacheMiss = errors.New("cache: item not found")
+	ErrKeyExists = cache.ErrKeyExists
+	ErrCacheMiss = cache.ErrCacheMiss
 )
 
 type unexportedInterface interface {
@@ -38,6 +42,9 @@ type unexportedInterface interface {
 type item struct {
 	Object     interface{}
 	Expiration *time.Time
+	Tags       []string
+	entry      *evictEntry
+	size       int64
 }
 
 // Returns true if the item has expired.
@@ -58,6 +65,15 @@ type innerMemeoryCache struct {
 	defaultExpiration time.Duration
 	items             map[string]*item
 	janitor           *janitor
+	maxEntries        int
+	maxBytes          int64
+	evictor           evictor
+	sizeFunc          func(string, interface{}) int64
+	bytes             int64
+	evictions         uint64
+	expirations       uint64
+	onEvicted         func(string, interface{}, EvictReason)
+	evicted           []evictedItem
 }
 
 // Add an item to the cache, replacing any existing item. If the duration is 0,
@@ -68,7 +84,7 @@ func (c *innerMemeoryCache) Set(k string, x interface{}, d time.Duration) {
 	c.set(k, x, d)
 	// TODO: Calls to mu.Unlock are currently not deferred because defer
 	// adds ~200 ns (as of go1.)
-	c.Unlock()
+	c.unlock()
 }
 
 func (c *innerMemeoryCache) set(k string, x interface{}, d time.Duration) {
@@ -80,23 +96,51 @@ func (c *innerMemeoryCache) set(k string, x interface{}, d time.Duration) {
 		t := time.Now().Add(d)
 		e = &t
 	}
+	if v, found := c.items[k]; found && (c.evictor != nil || c.onEvicted != nil) {
+		reason := EvictReasonReplaced
+		if v.Expired() {
+			reason = EvictReasonExpired
+		}
+		c.remove(k, reason)
+	}
+	if c.evictor != nil {
+		size := c.itemSize(k, x)
+		c.evict(1, size)
+		v := &item{
+			Object:     x,
+			Expiration: e,
+			size:       size,
+		}
+		c.items[k] = v
+		c.track(k, v)
+		return
+	}
 	c.items[k] = &item{
 		Object:     x,
 		Expiration: e,
 	}
 }
 
+// Add an item to the cache like Set and attach tags to it, so it can be
+// removed with DeleteTags.
+func (c *innerMemeoryCache) SetWithTags(k string, x interface{}, d time.Duration, tags []string) {
+	c.Lock()
+	c.set(k, x, d)
+	c.items[k].Tags = tags
+	c.unlock()
+}
+
 // Add an item to the cache only if an item doesn't already exist for the given
 // key, or if the existing item has expired. Returns an error otherwise.
 func (c *innerMemeoryCache) Add(k string, x interface{}, d time.Duration) error {
 	c.Lock()
 	_, found := c.get(k)
 	if found {
-		c.Unlock()
+		c.unlock()
 		return ErrKeyExists
 	}
 	c.set(k, x, d)
-	c.Unlock()
+	c.unlock()
 	return nil
 }
 
@@ -106,11 +150,11 @@ func (c *innerMemeoryCache) Replace(k string, x interface{}, d time.Duration) er
 	c.Lock()
 	_, found := c.get(k)
 	if !found {
-		c.Unlock()
+		c.unlock()
 		return fmt.Errorf("item %s doesn't exist", k)
 	}
 	c.set(k, x, d)
-	c.Unlock()
+	c.unlock()
 	return nil
 }
 
@@ -119,7 +163,7 @@ func (c *innerMemeoryCache) Replace(k string, x interface{}, d time.Duration) er
 func (c *innerMemeoryCache) Get(k string) (interface{}, bool) {
 	c.Lock()
 	x, found := c.get(k)
-	c.Unlock()
+	c.unlock()
 	return x, found
 }
 
@@ -129,12 +173,41 @@ func (c *innerMemeoryCache) get(k string) (interface{}, bool) {
 		return nil, false
 	}
 	if item.Expired() {
-		c.delete(k)
+		c.remove(k, EvictReasonExpired)
+		c.expirations++
 		return nil, false
 	}
+	c.touch(item)
 	return item.Object, true
 }
 
+// Get many items from the cache under a single lock. Returns the found items
+// and the keys that were not found.
+func (c *innerMemeoryCache) GetMulti(ks []string) (map[string]interface{}, []string) {
+	found := make(map[string]interface{}, len(ks))
+	var missing []string
+	c.Lock()
+	for _, k := range ks {
+		if x, ok := c.get(k); ok {
+			found[k] = x
+		} else {
+			missing = append(missing, k)
+		}
+	}
+	c.unlock()
+	return found, missing
+}
+
+// Add many items to the cache under a single lock, replacing any existing
+// items.
+func (c *innerMemeoryCache) SetMulti(items map[string]interface{}, d time.Duration) {
+	c.Lock()
+	for k, x := range items {
+		c.set(k, x, d)
+	}
+	c.unlock()
+}
+
 // Increment an item of type float32 or float64 by n. Returns an error if the
 // item's value is not floating point, if it was not found, or if it is not
 // possible to increment it by n. Pass a negative number to decrement the
@@ -316,28 +389,160 @@ func (c *innerMemeoryCache) Decrement(k string, n uint64) (uint64, error) {
 	return 0, fmt.Errorf("the value for %s is not an integer", k)
 }
 
+// IncrementInt64 adds n, which may be negative, to an integer item and
+// returns the new value. Unlike Increment and Decrement it uses signed
+// arithmetic, so decrementing a signed item below zero gives a negative
+// value, while an unsigned item that would go below zero is an error.
+// Strings and byte slices holding a decimal integer are updated in place.
+func (c *innerMemeoryCache) IncrementInt64(k string, n int64) (int64, error) {
+	c.Lock()
+	defer c.Unlock()
+	v, found := c.items[k]
+	if !found || v.Expired() {
+		return 0, ErrCacheMiss
+	}
+
+	var current int64
+	switch x := v.Object.(type) {
+	case int:
+		current = int64(x)
+	case int8:
+		current = int64(x)
+	case int16:
+		current = int64(x)
+	case int32:
+		current = int64(x)
+	case int64:
+		current = x
+	case uint:
+		current = int64(x)
+	case uintptr:
+		current = int64(x)
+	case uint8:
+		current = int64(x)
+	case uint16:
+		current = int64(x)
+	case uint32:
+		current = int64(x)
+	case uint64:
+		current = int64(x)
+	case string, []byte:
+		parsed, err := strconv.ParseInt(fmt.Sprintf("%s", x), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("the value for %s is not an integer", k)
+		}
+		current = parsed
+	default:
+		return 0, fmt.Errorf("the value for %s is not an integer", k)
+	}
+
+	result := current + n
+	switch v.Object.(type) {
+	case int:
+		v.Object = int(result)
+	case int8:
+		v.Object = int8(result)
+	case int16:
+		v.Object = int16(result)
+	case int32:
+		v.Object = int32(result)
+	case int64:
+		v.Object = result
+	case string:
+		v.Object = strconv.FormatInt(result, 10)
+	case []byte:
+		v.Object = []byte(strconv.FormatInt(result, 10))
+	default:
+		if result < 0 {
+			return 0, fmt.Errorf("the value for %s is unsigned and cannot go below zero", k)
+		}
+		switch v.Object.(type) {
+		case uint:
+			v.Object = uint(result)
+		case uintptr:
+			v.Object = uintptr(result)
+		case uint8:
+			v.Object = uint8(result)
+		case uint16:
+			v.Object = uint16(result)
+		case uint32:
+			v.Object = uint32(result)
+		case uint64:
+			v.Object = uint64(result)
+		}
+	}
+	return result, nil
+}
+
 // Delete an item from the cache. Does nothing if the key is not in the cache.
 func (c *innerMemeoryCache) Delete(k string) (found bool) {
 	c.Lock()
 	_, found = c.get(k)
-	c.delete(k)
-	c.Unlock()
+	c.remove(k, EvictReasonDeleted)
+	c.unlock()
 	return
 }
 
+// Delete many items from the cache under a single lock. Keys that are not in
+// the cache are ignored.
+func (c *innerMemeoryCache) DeleteMulti(ks []string) {
+	c.Lock()
+	for _, k := range ks {
+		c.remove(k, EvictReasonDeleted)
+	}
+	c.unlock()
+}
+
 func (c *innerMemeoryCache) delete(k string) {
+	if v, found := c.items[k]; found {
+		c.untrack(v)
+	}
 	delete(c.items, k)
 }
 
+// Delete all items carrying any of the given tags. Returns the number of
+// deleted items.
+func (c *innerMemeoryCache) DeleteTags(tags []string) int {
+	n := 0
+	c.Lock()
+	for k, v := range c.items {
+		for _, tag := range tags {
+			if slices.Contains(v.Tags, tag) {
+				c.remove(k, EvictReasonDeleted)
+				n++
+				break
+			}
+		}
+	}
+	c.unlock()
+	return n
+}
+
+// Delete all items whose key starts with prefix. Returns the number of
+// deleted items.
+func (c *innerMemeoryCache) DeletePrefix(prefix string) int {
+	n := 0
+	c.Lock()
+	for k := range c.items {
+		if strings.HasPrefix(k, prefix) {
+			c.remove(k, EvictReasonDeleted)
+			n++
+		}
+	}
+	c.unlock()
+	return n
+}
+
 // Delete all expired items from the cache.
 func (c *innerMemeoryCache) DeleteExpired() {
 	c.Lock()
 	for k, v := range c.items {
 		if v.Expired() {
-			c.delete(k)
+			c.remove(k, EvictReasonExpired)
+			c.expirations++
 		}
 	}
-	c.Unlock()
+	c.unlock()
 }
 
 // Write the cache's items (using Gob) to an io.Writer.
@@ -378,12 +583,17 @@ func (c *innerMemeoryCache) Load(r io.Reader) error {
 	items := map[string]*item{}
 	err := dec.Decode(&items)
 	if err == nil {
+		c.Lock()
 		for k, v := range items {
 			_, found := c.items[k]
 			if !found {
+				v.size = c.itemSize(k, v.Object)
 				c.items[k] = v
+				c.track(k, v)
 			}
 		}
+		c.evict(0, 0)
+		c.unlock()
 	}
 	return err
 }
@@ -406,8 +616,17 @@ func (c *innerMemeoryCache) LoadFile(fname string) error {
 // Delete all items from the cache.
 func (c *innerMemeoryCache) Flush() {
 	c.Lock()
+	if c.onEvicted != nil {
+		for k, v := range c.items {
+			c.evicted = append(c.evicted, evictedItem{key: k, value: v.Object, reason: EvictReasonDeleted})
+		}
+	}
 	c.items = map[string]*item{}
-	c.Unlock()
+	if c.evictor != nil {
+		c.evictor.reset()
+		c.bytes = 0
+	}
+	c.unlock()
 }
 
 type janitor struct {
@@ -0,0 +1,71 @@
+package memeorycache
+
+// EvictReason tells an OnEvicted callback why an item left the cache
+type EvictReason int
+
+const (
+	// EvictReasonExpired indicates the item expired
+	EvictReasonExpired EvictReason = iota
+	// EvictReasonDeleted indicates the item was deleted explicitly or flushed
+	EvictReasonDeleted
+	// EvictReasonReplaced indicates the item was overwritten by a new value
+	EvictReasonReplaced
+	// EvictReasonCapacity indicates the item was evicted to stay within the
+	// cache limits
+	EvictReasonCapacity
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case EvictReasonExpired:
+		return "expired"
+	case EvictReasonDeleted:
+		return "deleted"
+	case EvictReasonReplaced:
+		return "replaced"
+	case EvictReasonCapacity:
+		return "capacity"
+	default:
+		return "unknown"
+	}
+}
+
+type evictedItem struct {
+	key    string
+	value  interface{}
+	reason EvictReason
+}
+
+// Sets an (optional) function that is called with the key, value and reason
+// when an item leaves the cache, including expiry, explicit deletion,
+// replacement and capacity eviction. The function is called after the cache
+// lock is released, so it may use the cache. Set to nil to disable.
+func (c *innerMemeoryCache) OnEvicted(f func(key string, value interface{}, reason EvictReason)) {
+	c.Lock()
+	c.onEvicted = f
+	c.Unlock()
+}
+
+// remove deletes an item and records it for the OnEvicted callback
+func (c *innerMemeoryCache) remove(k string, reason EvictReason) {
+	if c.onEvicted != nil {
+		if v, found := c.items[k]; found {
+			c.evicted = append(c.evicted, evictedItem{key: k, value: v.Object, reason: reason})
+		}
+	}
+	c.delete(k)
+}
+
+// unlock releases the lock and then calls the OnEvicted callback for every
+// item removed while it was held
+func (c *innerMemeoryCache) unlock() {
+	evicted, onEvicted := c.evicted, c.onEvicted
+	c.evicted = nil
+	c.Unlock()
+	if onEvicted == nil {
+		return
+	}
+	for _, e := range evicted {
+		onEvicted(e.key, e.value, e.reason)
+	}
+}
@@ -0,0 +1,297 @@
+package memeorycache
+
+import (
+	"container/heap"
+	"container/list"
+	"reflect"
+	"runtime"
+	"time"
+)
+
+// EvictionPolicy decides which item is removed when a bounded cache is full
+type EvictionPolicy int
+
+const (
+	// EvictionLRU removes the least recently used item
+	EvictionLRU EvictionPolicy = iota
+	// EvictionLFU removes the least frequently used item, ties are broken by
+	// recency
+	EvictionLFU
+)
+
+// itemOverhead approximates the bytes used by the map entry and item struct
+const itemOverhead = 64
+
+// Options represents configuration options for a bounded MemeoryCache
+type Options struct {
+	// DefaultExpiration is used when an item is set with a zero duration
+	DefaultExpiration time.Duration
+	// CleanupInterval is the janitor interval, no janitor runs if it is less than one
+	CleanupInterval time.Duration
+	// MaxEntries is the maximum number of items, zero means unlimited
+	MaxEntries int
+	// MaxBytes is the approximate maximum memory used by items, zero means unlimited
+	MaxBytes int64
+	// EvictionPolicy decides which item is removed when a limit is exceeded
+	EvictionPolicy EvictionPolicy
+	// SizeFunc returns the approximate size of an item in bytes, defaults to
+	// a shallow estimate of the key and value
+	SizeFunc func(key string, value interface{}) int64
+}
+
+// Stats represents usage and eviction counters of a MemeoryCache
+type Stats struct {
+	// Entries is the number of items currently in the cache, including
+	// expired items that have not been cleaned up yet
+	Entries int
+	// Bytes is the approximate memory used by items, only tracked when
+	// MaxBytes is set
+	Bytes int64
+	// Evictions is the number of items removed to stay within the limits
+	Evictions uint64
+	// Expirations is the number of expired items removed
+	Expirations uint64
+}
+
+// evictEntry tracks an item in the eviction structure
+type evictEntry struct {
+	key     string
+	element *list.Element
+	index   int
+	hits    uint64
+	tick    uint64
+}
+
+type evictor interface {
+	add(entry *evictEntry)
+	access(entry *evictEntry)
+	remove(entry *evictEntry)
+	victim() (string, bool)
+	reset()
+}
+
+func newEvictor(policy EvictionPolicy) evictor {
+	if policy == EvictionLFU {
+		return &lfuEvictor{}
+	}
+	return &lruEvictor{ll: list.New()}
+}
+
+type lruEvictor struct {
+	ll *list.List
+}
+
+func (e *lruEvictor) add(entry *evictEntry) {
+	entry.element = e.ll.PushFront(entry)
+}
+
+func (e *lruEvictor) access(entry *evictEntry) {
+	e.ll.MoveToFront(entry.element)
+}
+
+func (e *lruEvictor) remove(entry *evictEntry) {
+	e.ll.Remove(entry.element)
+}
+
+func (e *lruEvictor) victim() (string, bool) {
+	back := e.ll.Back()
+	if back == nil {
+		return "", false
+	}
+	return back.Value.(*evictEntry).key, true
+}
+
+func (e *lruEvictor) reset() {
+	e.ll.Init()
+}
+
+type lfuEvictor struct {
+	entries lfuHeap
+	tick    uint64
+}
+
+func (e *lfuEvictor) add(entry *evictEntry) {
+	e.tick++
+	entry.tick = e.tick
+	heap.Push(&e.entries, entry)
+}
+
+func (e *lfuEvictor) access(entry *evictEntry) {
+	e.tick++
+	entry.hits++
+	entry.tick = e.tick
+	heap.Fix(&e.entries, entry.index)
+}
+
+func (e *lfuEvictor) remove(entry *evictEntry) {
+	heap.Remove(&e.entries, entry.index)
+}
+
+func (e *lfuEvictor) victim() (string, bool) {
+	if len(e.entries) == 0 {
+		return "", false
+	}
+	return e.entries[0].key, true
+}
+
+func (e *lfuEvictor) reset() {
+	e.entries = nil
+	e.tick = 0
+}
+
+// lfuHeap is a min-heap ordered by hits, then by last access
+type lfuHeap []*evictEntry
+
+func (h lfuHeap) Len() int {
+	return len(h)
+}
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].hits != h[j].hits {
+		return h[i].hits < h[j].hits
+	}
+	return h[i].tick < h[j].tick
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x interface{}) {
+	entry := x.(*evictEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return entry
+}
+
+// estimateSize returns a shallow estimate of the memory used by an item
+func estimateSize(key string, value interface{}) int64 {
+	size := int64(itemOverhead + len(key))
+	switch v := value.(type) {
+	case nil:
+		return size
+	case string:
+		return size + int64(len(v))
+	case []byte:
+		return size + int64(len(v))
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	size += int64(rv.Type().Size())
+	switch rv.Kind() {
+	case reflect.String:
+		size += int64(rv.Len())
+	case reflect.Slice, reflect.Array:
+		size += int64(rv.Len()) * int64(rv.Type().Elem().Size())
+	case reflect.Map:
+		size += int64(rv.Len()) * int64(rv.Type().Key().Size()+rv.Type().Elem().Size())
+	case reflect.Struct:
+		for i := 0; i < rv.NumField(); i++ {
+			switch f := rv.Field(i); f.Kind() {
+			case reflect.String, reflect.Slice:
+				size += int64(f.Len())
+			}
+		}
+	}
+	return size
+}
+
+// itemSize returns the approximate size of an item when memory is bounded
+func (c *innerMemeoryCache) itemSize(k string, x interface{}) int64 {
+	if c.evictor == nil || c.maxBytes <= 0 {
+		return 0
+	}
+	return c.sizeFunc(k, x)
+}
+
+// track adds an item to the eviction bookkeeping, v.size must already be set
+func (c *innerMemeoryCache) track(k string, v *item) {
+	if c.evictor == nil {
+		return
+	}
+	v.entry = &evictEntry{key: k}
+	c.evictor.add(v.entry)
+	c.bytes += v.size
+}
+
+// untrack removes an item from the eviction bookkeeping
+func (c *innerMemeoryCache) untrack(v *item) {
+	if c.evictor == nil || v.entry == nil {
+		return
+	}
+	c.evictor.remove(v.entry)
+	v.entry = nil
+	c.bytes -= v.size
+}
+
+// touch records an access to an item
+func (c *innerMemeoryCache) touch(v *item) {
+	if c.evictor == nil || v.entry == nil {
+		return
+	}
+	c.evictor.access(v.entry)
+}
+
+// evict removes items until an incoming item of the given size fits within
+// the limits. The incoming item is not tracked yet, so it is never chosen as
+// the victim; an item larger than MaxBytes is kept on its own.
+func (c *innerMemeoryCache) evict(incoming int, size int64) {
+	if c.evictor == nil {
+		return
+	}
+	for (c.maxEntries > 0 && len(c.items)+incoming > c.maxEntries) || (c.maxBytes > 0 && c.bytes+size > c.maxBytes) {
+		k, ok := c.evictor.victim()
+		if !ok {
+			return
+		}
+		c.remove(k, EvictReasonCapacity)
+		c.evictions++
+	}
+}
+
+// Stats returns usage and eviction counters of the cache
+func (c *innerMemeoryCache) Stats() Stats {
+	c.Lock()
+	stats := Stats{
+		Entries:     len(c.items),
+		Bytes:       c.bytes,
+		Evictions:   c.evictions,
+		Expirations: c.expirations,
+	}
+	c.Unlock()
+	return stats
+}
+
+// Return a new cache bounded by opts.MaxEntries and opts.MaxBytes. When a
+// limit is exceeded items are removed according to opts.EvictionPolicy.
+func NewMemeoryCacheWithOptions(opts Options) *MemeoryCache {
+	c := newCache(opts.DefaultExpiration)
+	if opts.MaxEntries > 0 || opts.MaxBytes > 0 {
+		c.maxEntries = opts.MaxEntries
+		c.maxBytes = opts.MaxBytes
+		c.evictor = newEvictor(opts.EvictionPolicy)
+		c.sizeFunc = opts.SizeFunc
+		if c.sizeFunc == nil {
+			c.sizeFunc = estimateSize
+		}
+	}
+	C := &MemeoryCache{c}
+	if opts.CleanupInterval > 0 {
+		runJanitor(c, opts.CleanupInterval)
+		runtime.SetFinalizer(C, stopJanitor)
+	}
+	return C
+}
@@ -1,6 +1,7 @@
 package memeorycache
 
 import (
+	"context"
 	"time"
 
 	"github.com/loongkirin/gdk/cache"
@@ -11,28 +12,50 @@ type InMemeoryStore struct {
 	cache *MemeoryCache
 }
 
+var (
+	_ cache.ContextCacheStore = (*InMemeoryStore)(nil)
+	_ cache.BatchCacheStore   = (*InMemeoryStore)(nil)
+	_ cache.TagCacheStore     = (*InMemeoryStore)(nil)
+	_ cache.PrefixCacheStore  = (*InMemeoryStore)(nil)
+)
+
 func NewInMemoryStore(defaultExpiration time.Duration) *InMemeoryStore {
 	return &InMemeoryStore{NewMemeoryCache(defaultExpiration, time.Minute)}
 }
 
-func (ms *InMemeoryStore) Get(key string) (string, error) {
+// NewInMemoryStoreWithOptions creates an InMemeoryStore on top of a bounded
+// MemeoryCache
+func NewInMemoryStoreWithOptions(opts Options) *InMemeoryStore {
+	if opts.CleanupInterval == 0 {
+		opts.CleanupInterval = time.Minute
+	}
+	return &InMemeoryStore{NewMemeoryCacheWithOptions(opts)}
+}
+
+// Stats returns usage and eviction counters of the underlying cache
+func (ms *InMemeoryStore) Stats() Stats {
+	return ms.cache.Stats()
+}
+
+// OnEvicted sets a function that is called when an item leaves the cache
+func (ms *InMemeoryStore) OnEvicted(f func(key string, value interface{}, reason EvictReason)) {
+	ms.cache.OnEvicted(f)
+}
+
+func (ms *InMemeoryStore) Get(_ context.Context, key string) (string, error) {
 	val, found := ms.cache.Get(key)
 	if !found {
 		return "", ErrCacheMiss
 	}
-	v, err := util.Serialize(val)
-	if err != nil {
-		return "", err
-	}
-	return string(v), nil
+	return encodeValue(val)
 }
 
-func (ms *InMemeoryStore) Set(key string, value interface{}, expires time.Duration) error {
+func (ms *InMemeoryStore) Set(_ context.Context, key string, value interface{}, expires time.Duration) error {
 	ms.cache.Set(key, value, expires)
 	return nil
 }
 
-func (ms *InMemeoryStore) Add(key string, value interface{}, expires time.Duration) error {
+func (ms *InMemeoryStore) Add(_ context.Context, key string, value interface{}, expires time.Duration) error {
 	err := ms.cache.Add(key, value, expires)
 	if err == ErrKeyExists {
 		return cache.ErrKeyExists
@@ -40,37 +63,81 @@ func (ms *InMemeoryStore) Add(key string, value interface{}, expires time.Durati
 	return err
 }
 
-func (ms *InMemeoryStore) Replace(key string, value interface{}, expires time.Duration) error {
+func (ms *InMemeoryStore) Replace(_ context.Context, key string, value interface{}, expires time.Duration) error {
 	if err := ms.cache.Replace(key, value, expires); err != nil {
 		return cache.ErrNotStored
 	}
 	return nil
 }
 
-func (ms *InMemeoryStore) Delete(key string) error {
-	if found := ms.cache.Delete(key); !found {
-		return ErrCacheMiss
-	}
+// Delete removes an item, it does nothing if the key is not in the cache
+func (ms *InMemeoryStore) Delete(_ context.Context, key string) error {
+	ms.cache.Delete(key)
 	return nil
 }
 
-func (ms *InMemeoryStore) Increment(key string, value int64) (int64, error) {
-	newValue, err := ms.cache.Increment(key, uint64(value))
-	if err == ErrCacheMiss {
-		return 0, ErrCacheMiss
-	}
-	return int64(newValue), err
+func (ms *InMemeoryStore) Increment(_ context.Context, key string, value int64) (int64, error) {
+	return ms.cache.IncrementInt64(key, value)
 }
 
-func (ms *InMemeoryStore) Decrement(key string, value int64) (int64, error) {
-	newValue, err := ms.cache.Decrement(key, uint64(value))
-	if err == ErrCacheMiss {
-		return 0, ErrCacheMiss
-	}
-	return int64(newValue), err
+// Decrement subtracts value from an integer item, the result of a signed
+// item may be negative like in Redis
+func (ms *InMemeoryStore) Decrement(_ context.Context, key string, value int64) (int64, error) {
+	return ms.cache.IncrementInt64(key, -value)
 }
 
-func (ms *InMemeoryStore) Flush() error {
+func (ms *InMemeoryStore) Flush(_ context.Context) error {
 	ms.cache.Flush()
 	return nil
 }
+
+func (ms *InMemeoryStore) GetMulti(_ context.Context, keys []string) (map[string]string, []string, error) {
+	found, misses := ms.cache.GetMulti(keys)
+	values := make(map[string]string, len(found))
+	for key, val := range found {
+		v, err := encodeValue(val)
+		if err != nil {
+			return nil, nil, err
+		}
+		values[key] = v
+	}
+	return values, misses, nil
+}
+
+func (ms *InMemeoryStore) SetMulti(_ context.Context, items map[string]interface{}, expires time.Duration) error {
+	ms.cache.SetMulti(items, expires)
+	return nil
+}
+
+func (ms *InMemeoryStore) DeleteMulti(_ context.Context, keys []string) error {
+	ms.cache.DeleteMulti(keys)
+	return nil
+}
+
+func (ms *InMemeoryStore) SetWithTags(_ context.Context, key string, value interface{}, expires time.Duration, tags ...string) error {
+	ms.cache.SetWithTags(key, value, expires, tags)
+	return nil
+}
+
+func (ms *InMemeoryStore) InvalidateTags(_ context.Context, tags ...string) error {
+	ms.cache.DeleteTags(tags)
+	return nil
+}
+
+func (ms *InMemeoryStore) DeletePrefix(_ context.Context, prefix string) error {
+	ms.cache.DeletePrefix(prefix)
+	return nil
+}
+
+// encodeValue returns the string form of a cached value. Strings are returned
+// as they are, so a string reads back the same from every backend.
+func encodeValue(val interface{}) (string, error) {
+	if s, ok := val.(string); ok {
+		return s, nil
+	}
+	v, err := util.Serialize(val)
+	if err != nil {
+		return "", err
+	}
+	return string(v), nil
+}
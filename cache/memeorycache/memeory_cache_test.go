@@ -2,12 +2,17 @@ package memeorycache
 
 import (
 	"bytes"
+	"fmt"
 	"os"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/loongkirin/gdk/cache"
+	"github.com/loongkirin/gdk/cache/cachetest"
 )
 
 type TestStruct struct {
@@ -794,3 +799,158 @@ func BenchmarkMutexMapSetDeleteSingleLock(b *testing.B) {
 		mu.Unlock()
 	}
 }
+
+func TestMultiOperations(t *testing.T) {
+	tc := NewMemeoryCache(0, 0)
+	tc.SetMulti(map[string]interface{}{"a": 1, "b": 2}, 0)
+
+	found, missing := tc.GetMulti([]string{"a", "b", "c"})
+	if len(found) != 2 || found["a"] != 1 || found["b"] != 2 {
+		t.Error("unexpected found items:", found)
+	}
+	if len(missing) != 1 || missing[0] != "c" {
+		t.Error("unexpected missing keys:", missing)
+	}
+
+	tc.DeleteMulti([]string{"a", "c"})
+	if _, found := tc.Get("a"); found {
+		t.Error("a was found after DeleteMulti")
+	}
+	if _, found := tc.Get("b"); !found {
+		t.Error("b was not found after DeleteMulti")
+	}
+}
+
+func TestDeleteTagsAndPrefix(t *testing.T) {
+	tc := NewMemeoryCache(0, 0)
+	tc.SetWithTags("order:1", 1, 0, []string{"user:1", "orders"})
+	tc.SetWithTags("order:2", 2, 0, []string{"user:2", "orders"})
+	tc.Set("profile:1", 3, 0)
+
+	if n := tc.DeleteTags([]string{"user:1"}); n != 1 {
+		t.Error("DeleteTags removed", n, "items, want 1")
+	}
+	if _, found := tc.Get("order:2"); !found {
+		t.Error("order:2 was removed by an unrelated tag")
+	}
+	if n := tc.DeletePrefix("profile:"); n != 1 {
+		t.Error("DeletePrefix removed", n, "items, want 1")
+	}
+	if n := tc.DeleteTags([]string{"orders"}); n != 1 {
+		t.Error("DeleteTags removed", n, "items, want 1")
+	}
+	if len(tc.items) != 0 {
+		t.Error("cache is not empty")
+	}
+}
+
+func TestMaxEntriesLRU(t *testing.T) {
+	tc := NewMemeoryCacheWithOptions(Options{MaxEntries: 2, EvictionPolicy: EvictionLRU})
+	tc.Set("a", 1, 0)
+	tc.Set("b", 2, 0)
+	tc.Get("a")
+	tc.Set("c", 3, 0)
+
+	if _, found := tc.Get("b"); found {
+		t.Error("b should have been evicted as least recently used")
+	}
+	if _, found := tc.Get("a"); !found {
+		t.Error("a was evicted")
+	}
+	if stats := tc.Stats(); stats.Entries != 2 || stats.Evictions != 1 {
+		t.Error("unexpected stats:", stats)
+	}
+}
+
+func TestMaxEntriesLFU(t *testing.T) {
+	tc := NewMemeoryCacheWithOptions(Options{MaxEntries: 2, EvictionPolicy: EvictionLFU})
+	tc.Set("a", 1, 0)
+	tc.Set("b", 2, 0)
+	tc.Get("a")
+	tc.Get("a")
+	tc.Get("b")
+	tc.Set("c", 3, 0)
+	tc.Get("c")
+	tc.Get("c")
+	tc.Get("c")
+	tc.Set("d", 4, 0)
+
+	if _, found := tc.Get("b"); found {
+		t.Error("b should have been evicted as least frequently used")
+	}
+	if _, found := tc.Get("c"); !found {
+		t.Error("c was evicted")
+	}
+	if stats := tc.Stats(); stats.Evictions != 2 {
+		t.Error("unexpected stats:", stats)
+	}
+}
+
+func TestMaxBytes(t *testing.T) {
+	tc := NewMemeoryCacheWithOptions(Options{
+		MaxBytes: 100,
+		SizeFunc: func(key string, value interface{}) int64 {
+			return int64(len(value.(string)))
+		},
+	})
+	tc.Set("a", strings.Repeat("a", 40), 0)
+	tc.Set("b", strings.Repeat("b", 40), 0)
+	tc.Set("c", strings.Repeat("c", 40), 0)
+
+	if _, found := tc.Get("a"); found {
+		t.Error("a should have been evicted")
+	}
+	if stats := tc.Stats(); stats.Bytes != 80 || stats.Evictions != 1 {
+		t.Error("unexpected stats:", stats)
+	}
+	tc.Delete("b")
+	if stats := tc.Stats(); stats.Bytes != 40 {
+		t.Error("unexpected stats after delete:", stats)
+	}
+}
+
+func TestOnEvicted(t *testing.T) {
+	tc := NewMemeoryCacheWithOptions(Options{MaxEntries: 2})
+	reasons := map[string]EvictReason{}
+	tc.OnEvicted(func(k string, v interface{}, reason EvictReason) {
+		reasons[fmt.Sprint(k, "=", v)] = reason
+		// The callback runs outside the lock and may use the cache
+		tc.Get(k)
+	})
+
+	tc.Set("a", 1, 0)
+	tc.Set("a", 2, 0)
+	tc.Delete("a")
+	tc.Set("b", 3, time.Nanosecond)
+	<-time.After(time.Millisecond)
+	tc.DeleteExpired()
+	tc.Set("c", 4, 0)
+	tc.Set("d", 5, 0)
+	tc.Set("e", 6, 0)
+
+	want := map[string]EvictReason{
+		"a=1": EvictReasonReplaced,
+		"a=2": EvictReasonDeleted,
+		"b=3": EvictReasonExpired,
+	}
+	for k, reason := range want {
+		if reasons[k] != reason {
+			t.Errorf("%s was evicted with reason %v, want %v", k, reasons[k], reason)
+		}
+	}
+	capacity := 0
+	for _, reason := range reasons {
+		if reason == EvictReasonCapacity {
+			capacity++
+		}
+	}
+	if capacity == 0 {
+		t.Error("no capacity eviction was reported")
+	}
+}
+
+func TestInMemoryStoreConformance(t *testing.T) {
+	cachetest.Run(t, func(t *testing.T) cache.ContextCacheStore {
+		return NewInMemoryStore(time.Minute)
+	})
+}
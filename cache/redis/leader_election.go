@@ -0,0 +1,228 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/loongkirin/gdk/telemetry"
+	"github.com/loongkirin/gdk/util"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	// 定义指标
+	leaderElectionIsLeaderDef = telemetry.MetricDefinition[float64]{
+		Name:        "leader_election_is_leader",
+		Description: "Whether this instance is the leader (1) or not (0)",
+		Unit:        "1",
+		Kind:        telemetry.KindGauge,
+	}
+
+	leaderElectionTransitionsDef = telemetry.MetricDefinition[float64]{
+		Name:        "leader_election_transitions_total",
+		Description: "Total number of leadership transitions of this instance",
+		Unit:        "1",
+		Kind:        telemetry.KindCounter,
+	}
+)
+
+// LeaderElectionOptions represents configuration options for LeaderElector
+type LeaderElectionOptions struct {
+	// Identity identifies this instance, it is stored as the lock value.
+	// Defaults to a generated id.
+	Identity string
+	// LeaseDuration is the expiration of the leader lock
+	LeaseDuration time.Duration
+	// RenewInterval is the interval at which the leader refreshes the lock,
+	// it must be shorter than LeaseDuration
+	RenewInterval time.Duration
+	// RetryPeriod is the interval at which a follower campaigns again
+	RetryPeriod time.Duration
+	// OnStartedLeading is called in its own goroutine when this instance
+	// becomes the leader. ctx is cancelled when leadership is lost.
+	OnStartedLeading func(ctx context.Context)
+	// OnStoppedLeading is called when this instance stops being the leader
+	OnStoppedLeading func()
+}
+
+// DefaultLeaderElectionOptions returns the default options for LeaderElector
+func DefaultLeaderElectionOptions() LeaderElectionOptions {
+	return LeaderElectionOptions{
+		LeaseDuration: 15 * time.Second,
+		RenewInterval: 5 * time.Second,
+		RetryPeriod:   2 * time.Second,
+	}
+}
+
+// LeaderElector elects one leader among the instances campaigning on the
+// same key. Leadership is a RedisLock kept alive with AutoRefresh, the leader
+// steps down as soon as a refresh fails, so at most one instance believes it
+// leads as long as RenewInterval is well below LeaseDuration.
+type LeaderElector struct {
+	client       redis.UniversalClient
+	key          string
+	options      LeaderElectionOptions
+	dynamicMeter *telemetry.DynamicMeter[float64]
+	leading      int32 // atomic
+	running      int32 // atomic
+}
+
+// NewLeaderElector creates a LeaderElector campaigning on key. dynamicMeter
+// records leadership metrics, it may be nil.
+func NewLeaderElector(client redis.UniversalClient, key string, dynamicMeter *telemetry.DynamicMeter[float64], opts LeaderElectionOptions) (*LeaderElector, error) {
+	if client == nil {
+		return nil, errors.New("redis client is required")
+	}
+	if key == "" {
+		return nil, ErrLockKeyRequired
+	}
+
+	defaults := DefaultLeaderElectionOptions()
+	if opts.Identity == "" {
+		opts.Identity = util.GenerateId()
+	}
+	if opts.LeaseDuration <= 0 {
+		opts.LeaseDuration = defaults.LeaseDuration
+	}
+	if opts.RenewInterval <= 0 {
+		opts.RenewInterval = opts.LeaseDuration / 3
+	}
+	if opts.RenewInterval >= opts.LeaseDuration {
+		return nil, errors.New("renew interval must be shorter than lease duration")
+	}
+	if opts.RetryPeriod <= 0 {
+		opts.RetryPeriod = defaults.RetryPeriod
+	}
+
+	if dynamicMeter != nil {
+		for _, def := range []telemetry.MetricDefinition[float64]{leaderElectionIsLeaderDef, leaderElectionTransitionsDef} {
+			if _, err := dynamicMeter.GetOrCreateMetric(def); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &LeaderElector{
+		client:       client,
+		key:          key,
+		options:      opts,
+		dynamicMeter: dynamicMeter,
+	}, nil
+}
+
+// Run campaigns for leadership until ctx is done. When ctx is done while
+// leading, the lock is released so another instance can take over at once.
+func (e *LeaderElector) Run(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&e.running, 0, 1) {
+		return errors.New("leader elector is already running")
+	}
+	defer atomic.StoreInt32(&e.running, 0)
+
+	lock, err := NewRedisLock(e.client, e.key, e.options.Identity, e.options.LeaseDuration)
+	if err != nil {
+		return err
+	}
+	e.recordLeading(ctx, false)
+
+	ticker := time.NewTicker(e.options.RetryPeriod)
+	defer ticker.Stop()
+	for {
+		if err := lock.Lock(ctx); err == nil {
+			e.lead(ctx, lock)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// lead holds leadership until ctx is done or a refresh fails
+func (e *LeaderElector) lead(ctx context.Context, lock *RedisLock) {
+	leaderCtx, cancel := context.WithCancel(ctx)
+	errCh, stop := lock.AutoRefresh(leaderCtx, e.options.RenewInterval)
+
+	atomic.StoreInt32(&e.leading, 1)
+	e.recordLeading(ctx, true)
+	e.recordTransition(ctx, "started")
+
+	if e.options.OnStartedLeading != nil {
+		go e.options.OnStartedLeading(leaderCtx)
+	}
+
+	// 续约失败或 ctx 结束时立即让出领导权
+	<-errCh
+	stop()
+	cancel()
+
+	atomic.StoreInt32(&e.leading, 0)
+	releaseCtx, releaseCancel := context.WithTimeout(context.WithoutCancel(ctx), e.options.RenewInterval)
+	if err := lock.Unlock(releaseCtx); err != nil && !errors.Is(err, ErrLockNotHeld) {
+		// 释放失败时锁在租约到期后自然过期，重置本地状态以便再次竞选
+		lock.reset()
+	}
+	releaseCancel()
+
+	e.recordLeading(ctx, false)
+	e.recordTransition(ctx, "stopped")
+	if e.options.OnStoppedLeading != nil {
+		e.options.OnStoppedLeading()
+	}
+}
+
+// IsLeader reports whether this instance currently leads
+func (e *LeaderElector) IsLeader() bool {
+	return atomic.LoadInt32(&e.leading) == 1
+}
+
+// Identity returns the identity of this instance
+func (e *LeaderElector) Identity() string {
+	return e.options.Identity
+}
+
+// Leader returns the identity of the current leader, empty when there is none
+func (e *LeaderElector) Leader(ctx context.Context) (string, error) {
+	identity, err := e.client.Get(ctx, e.key).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return identity, err
+}
+
+func (e *LeaderElector) attributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("election", e.key),
+		attribute.String("identity", e.options.Identity),
+	}
+}
+
+func (e *LeaderElector) recordLeading(ctx context.Context, leading bool) {
+	if e.dynamicMeter == nil {
+		return
+	}
+	value := 0.0
+	if leading {
+		value = 1
+	}
+	e.dynamicMeter.RecordMetric(context.WithoutCancel(ctx), telemetry.MetricValue[float64]{
+		Name:       leaderElectionIsLeaderDef.Name,
+		Value:      value,
+		Attributes: e.attributes(),
+	})
+}
+
+func (e *LeaderElector) recordTransition(ctx context.Context, transition string) {
+	if e.dynamicMeter == nil {
+		return
+	}
+	e.dynamicMeter.RecordMetric(context.WithoutCancel(ctx), telemetry.MetricValue[float64]{
+		Name:       leaderElectionTransitionsDef.Name,
+		Value:      1,
+		Attributes: append(e.attributes(), attribute.String("transition", transition)),
+	})
+}
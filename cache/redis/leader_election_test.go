@@ -0,0 +1,133 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestLeaderElector(t *testing.T) {
+	mr, client := newTestRedisClient(t)
+
+	newElector := func(identity string, started, stopped chan string) *LeaderElector {
+		opts := LeaderElectionOptions{
+			Identity:         identity,
+			LeaseDuration:    time.Second,
+			RenewInterval:    20 * time.Millisecond,
+			RetryPeriod:      20 * time.Millisecond,
+			OnStartedLeading: func(ctx context.Context) { started <- identity },
+			OnStoppedLeading: func() { stopped <- identity },
+		}
+		e, err := NewLeaderElector(client, "leader", nil, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+	expect := func(ch chan string, want string) {
+		t.Helper()
+		select {
+		case got := <-ch:
+			if got != want {
+				t.Fatalf("got %s, want %s", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %s", want)
+		}
+	}
+
+	started := make(chan string, 4)
+	stopped := make(chan string, 4)
+	ctxA, cancelA := context.WithCancel(context.Background())
+	a := newElector("a", started, stopped)
+	go a.Run(ctxA)
+	expect(started, "a")
+
+	ctxB, cancelB := context.WithCancel(context.Background())
+	defer cancelB()
+	b := newElector("b", started, stopped)
+	go b.Run(ctxB)
+	if leader, _ := b.Leader(ctxB); leader != "a" || !a.IsLeader() || b.IsLeader() {
+		t.Fatalf("leader = %s, a leads %v, b leads %v", leader, a.IsLeader(), b.IsLeader())
+	}
+
+	// 释放领导权后另一个实例接管
+	cancelA()
+	expect(stopped, "a")
+	expect(started, "b")
+
+	// 续约失败时主动让出领导权
+	mr.Set("leader", "someone-else")
+	expect(stopped, "b")
+	if b.IsLeader() {
+		t.Error("b still leads after losing the lock")
+	}
+}
+
+// failUnlockHook fails the unlock script while fail is set
+type failUnlockHook struct {
+	fail *atomic.Bool
+}
+
+func (h failUnlockHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h failUnlockHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if args := cmd.Args(); h.fail.Load() && len(args) > 1 && args[1] == unlockScript {
+			err := errors.New("network error")
+			cmd.SetErr(err)
+			return err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h failUnlockHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestLeaderElectorCampaignsAfterUnlockFailure(t *testing.T) {
+	mr, client := newTestRedisClient(t)
+	var fail atomic.Bool
+	fail.Store(true)
+	client.AddHook(failUnlockHook{fail: &fail})
+
+	started := make(chan struct{}, 4)
+	stopped := make(chan struct{}, 4)
+	e, err := NewLeaderElector(client, "leader", nil, LeaderElectionOptions{
+		Identity:         "a",
+		LeaseDuration:    time.Second,
+		RenewInterval:    20 * time.Millisecond,
+		RetryPeriod:      20 * time.Millisecond,
+		OnStartedLeading: func(ctx context.Context) { started <- struct{}{} },
+		OnStoppedLeading: func() { stopped <- struct{}{} },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go e.Run(ctx)
+
+	wait := func(ch chan struct{}, what string) {
+		t.Helper()
+		select {
+		case <-ch:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+	wait(started, "leadership")
+
+	// 续约失败后释放锁也失败，锁过期后仍能再次竞选
+	mr.Set("leader", "someone-else")
+	wait(stopped, "step down")
+	mr.Del("leader")
+	wait(started, "leadership after a failed unlock")
+}
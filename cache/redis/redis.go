@@ -14,25 +14,38 @@ import (
 
 type RedisClient struct {
 	RedisConfig *RedisConfig
-	master      *redis.Client
-	slaves      []*redis.Client
+	master      redis.UniversalClient
+	slaves      []redis.UniversalClient
 	lock        sync.RWMutex
 	current     int
+	replicas    []ReplicaStatus
+	stopProbe   chan struct{}
+	probeDone   chan struct{}
+	stopOnce    sync.Once
 	tracer      trace.Tracer
 	meter       metric.Meter
 }
 
+// NewRedisClient connects to Redis according to cfg.Mode. In standalone mode
+// the master and slaves are plain clients, in sentinel mode the master is a
+// failover client and in cluster mode a cluster client. All of them are
+// returned as redis.UniversalClient.
 func NewRedisClient(cfg *RedisConfig) (*RedisClient, error) {
-	// 创建 master 客户端
-	master := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%d", cfg.Master.Host, cfg.Master.Port),
-		Password: cfg.Master.Password,
-		DB:       cfg.Master.DB,
-		PoolSize: cfg.PoolSize,
-	})
-
-	if err := master.Ping(context.Background()).Err(); err != nil {
-		return nil, fmt.Errorf("failed to connect to master redis: %w", err)
+	var master redis.UniversalClient
+	var slaves []redis.UniversalClient
+	var err error
+	switch cfg.Mode {
+	case "", RedisModeStandalone:
+		master, slaves, err = newStandaloneClients(cfg)
+	case RedisModeSentinel:
+		master, slaves, err = newSentinelClients(cfg)
+	case RedisModeCluster:
+		master, slaves, err = newClusterClients(cfg)
+	default:
+		err = fmt.Errorf("unsupported redis mode: %s", cfg.Mode)
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	var meter metric.Meter
@@ -41,39 +54,145 @@ func NewRedisClient(cfg *RedisConfig) (*RedisClient, error) {
 		// 创建 meter 和 tracer
 		meter = otel.Meter("redis-client")
 		tracer = otel.Tracer("redis-client")
-		reportRedisMetrics(master, meter, "master", cfg.Master)
+		reportRedisMetrics(master, meter, "master", cfg.metricsConnection())
+		for i, slave := range slaves {
+			slaveCfg := cfg.metricsConnection()
+			if cfg.Mode != RedisModeSentinel && cfg.Mode != RedisModeCluster && i < len(cfg.Slaves) {
+				slaveCfg = cfg.Slaves[i]
+			}
+			reportRedisMetrics(slave, meter, fmt.Sprintf("slave_%d", i), slaveCfg)
+		}
+	}
+
+	client := &RedisClient{
+		RedisConfig: cfg,
+		master:      master,
+		slaves:      slaves,
+		tracer:      tracer,
+		meter:       meter,
+	}
+	client.startReplicaProbe()
+	// sentinel 模式只有聚合状态，不上报单个从节点的指标
+	if cfg.EnableMetrics && cfg.Mode != RedisModeSentinel {
+		reportReplicaMetrics(client, meter)
 	}
+	return client, nil
+}
 
-	var slaves []*redis.Client
-	for i, slaveCfg := range cfg.Slaves {
+func newStandaloneClients(cfg *RedisConfig) (redis.UniversalClient, []redis.UniversalClient, error) {
+	// 创建 master 客户端
+	master := redis.NewClient(&redis.Options{
+		Addr:     cfg.Master.addr(),
+		Password: cfg.Master.Password,
+		DB:       cfg.Master.DB,
+		PoolSize: cfg.PoolSize,
+	})
+
+	if err := master.Ping(context.Background()).Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to master redis: %w", err)
+	}
+
+	var slaves []redis.UniversalClient
+	for _, slaveCfg := range cfg.Slaves {
 		slave := redis.NewClient(&redis.Options{
-			Addr:     fmt.Sprintf("%s:%d", slaveCfg.Host, slaveCfg.Port),
+			Addr:     slaveCfg.addr(),
 			Password: slaveCfg.Password,
 			DB:       slaveCfg.DB,
 			PoolSize: cfg.PoolSize,
 		})
 
 		if err := slave.Ping(context.Background()).Err(); err != nil {
-			return nil, fmt.Errorf("failed to connect to slave redis: %w", err)
+			return nil, nil, fmt.Errorf("failed to connect to slave redis: %w", err)
 		}
+		slaves = append(slaves, slave)
+	}
+	return master, slaves, nil
+}
 
-		if cfg.EnableMetrics {
-			reportRedisMetrics(slave, meter, fmt.Sprintf("slave_%d", i), slaveCfg)
+func newSentinelClients(cfg *RedisConfig) (redis.UniversalClient, []redis.UniversalClient, error) {
+	sentinelCfg := cfg.Sentinel
+	if sentinelCfg.MasterName == "" || len(sentinelCfg.Sentinels) == 0 {
+		return nil, nil, fmt.Errorf("sentinel mode requires master_name and sentinels")
+	}
+
+	failoverOptions := func(replicaOnly bool) *redis.FailoverOptions {
+		return &redis.FailoverOptions{
+			MasterName:       sentinelCfg.MasterName,
+			SentinelAddrs:    addrs(sentinelCfg.Sentinels),
+			SentinelPassword: sentinelCfg.SentinelPassword,
+			Password:         sentinelCfg.Password,
+			DB:               sentinelCfg.DB,
+			PoolSize:         cfg.PoolSize,
+			ReplicaOnly:      replicaOnly,
+		}
+	}
+
+	// master 客户端在故障转移后会自动切换到新的主节点
+	master := redis.NewFailoverClient(failoverOptions(false))
+	if err := master.Ping(context.Background()).Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to sentinel master redis: %w", err)
+	}
+
+	var slaves []redis.UniversalClient
+	if sentinelCfg.ReplicaRead {
+		slave := redis.NewFailoverClient(failoverOptions(true))
+		if err := slave.Ping(context.Background()).Err(); err != nil {
+			return nil, nil, fmt.Errorf("failed to connect to sentinel replica redis: %w", err)
 		}
 		slaves = append(slaves, slave)
 	}
+	return master, slaves, nil
+}
 
-	return &RedisClient{
-		RedisConfig: cfg,
-		master:      master,
-		slaves:      slaves,
-		tracer:      tracer,
-		meter:       meter,
-	}, nil
+func newClusterClients(cfg *RedisConfig) (redis.UniversalClient, []redis.UniversalClient, error) {
+	clusterCfg := cfg.Cluster
+	if len(clusterCfg.Nodes) == 0 {
+		return nil, nil, fmt.Errorf("cluster mode requires nodes")
+	}
+
+	master := redis.NewClusterClient(&redis.ClusterOptions{
+		Addrs:    addrs(clusterCfg.Nodes),
+		Password: clusterCfg.Password,
+		PoolSize: cfg.PoolSize,
+	})
+	if err := master.Ping(context.Background()).Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to redis cluster: %w", err)
+	}
+
+	var slaves []redis.UniversalClient
+	if clusterCfg.ReadOnly {
+		// 只读客户端将读请求路由到从节点
+		slave := redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:          addrs(clusterCfg.Nodes),
+			Password:       clusterCfg.Password,
+			PoolSize:       cfg.PoolSize,
+			ReadOnly:       true,
+			RouteByLatency: true,
+		})
+		if err := slave.Ping(context.Background()).Err(); err != nil {
+			return nil, nil, fmt.Errorf("failed to connect to redis cluster replicas: %w", err)
+		}
+		slaves = append(slaves, slave)
+	}
+	return master, slaves, nil
+}
+
+// metricsConnection returns the connection used to label the metrics of the
+// master client
+func (cfg *RedisConfig) metricsConnection() RedisConnection {
+	switch cfg.Mode {
+	case RedisModeSentinel:
+		return RedisConnection{Host: cfg.Sentinel.MasterName, DB: cfg.Sentinel.DB}
+	case RedisModeCluster:
+		if len(cfg.Cluster.Nodes) > 0 {
+			return cfg.Cluster.Nodes[0]
+		}
+	}
+	return cfg.Master
 }
 
 // 添加指标报告函数
-func reportRedisMetrics(client *redis.Client, meter metric.Meter, dbRole string, dbConntection RedisConnection) {
+func reportRedisMetrics(client redis.UniversalClient, meter metric.Meter, dbRole string, dbConntection RedisConnection) {
 	// 创建指标
 	poolSize, _ := meter.Int64ObservableGauge(
 		"redis.pool.size",
@@ -130,6 +249,7 @@ func (r *RedisClient) HealthCheck() error {
 
 // 添加关闭方法
 func (r *RedisClient) Close() error {
+	r.stopReplicaProbe()
 	if err := r.master.Close(); err != nil {
 		return fmt.Errorf("failed to close master: %w", err)
 	}
@@ -150,11 +270,11 @@ func (r *RedisClient) WithTrace(ctx context.Context, cmd string) (context.Contex
 	)
 }
 
-func (r *RedisClient) GetMasterDb() *redis.Client {
+func (r *RedisClient) GetMasterDb() redis.UniversalClient {
 	return r.master
 }
 
-func (r *RedisClient) GetSlaveDb() *redis.Client {
+func (r *RedisClient) GetSlaveDb() redis.UniversalClient {
 	if len(r.slaves) == 0 {
 		return r.master
 	}
@@ -162,6 +282,12 @@ func (r *RedisClient) GetSlaveDb() *redis.Client {
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
-	r.current = (r.current + 1) % len(r.slaves)
-	return r.slaves[r.current]
+	// 跳过不健康或延迟过大的从节点，全部不可用时回退到 master
+	for range r.slaves {
+		r.current = (r.current + 1) % len(r.slaves)
+		if r.replicas[r.current].State == ReplicaHealthy {
+			return r.slaves[r.current]
+		}
+	}
+	return r.master
 }
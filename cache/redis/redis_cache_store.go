@@ -2,7 +2,9 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/loongkirin/gdk/cache"
@@ -10,13 +12,56 @@ import (
 )
 
 type RedisStore struct {
-	redisClient *redis.Client
+	redisClient redis.UniversalClient
 	Prekey      string
 	Expiration  time.Duration
-	Context     context.Context
+
+	// Context is used by the calls made with a nil context.
+	//
+	// Deprecated: pass the context to each method instead.
+	Context context.Context
 }
 
-func NewRedisStore(redisClient *redis.Client, prekey string, defaultExpiration time.Duration) *RedisStore {
+// ErrFlushWithoutPrefix is returned by Flush and DeletePrefix when neither the
+// store's Prekey nor the prefix is set, they would delete every key of the
+// database
+var ErrFlushWithoutPrefix = errors.New("redis store without prekey cannot be flushed")
+
+var (
+	_ cache.ContextCacheStore = (*RedisStore)(nil)
+	_ cache.BatchCacheStore   = (*RedisStore)(nil)
+	_ cache.TagCacheStore     = (*RedisStore)(nil)
+	_ cache.PrefixCacheStore  = (*RedisStore)(nil)
+)
+
+const (
+	// scanBatchSize is the COUNT hint of SCAN and the size of UNLINK batches
+	scanBatchSize = 500
+
+	// 标签脚本：记录标签下的 key，标签集合的过期时间不短于其中任何 key
+	tagScript = `
+		local existed = redis.call("exists", KEYS[1])
+		redis.call("sadd", KEYS[1], ARGV[1])
+		local ttl = tonumber(ARGV[2])
+		if ttl <= 0 then
+			redis.call("persist", KEYS[1])
+			return 1
+		end
+		local current = redis.call("pttl", KEYS[1])
+		if existed == 0 or (current >= 0 and current < ttl) then
+			redis.call("pexpire", KEYS[1], ttl)
+		end
+		return 1`
+
+	// 自增脚本：key 不存在时返回 nil，不会创建新 key
+	incrementScript = `
+		if redis.call("exists", KEYS[1]) == 0 then
+			return false
+		end
+		return redis.call("incrby", KEYS[1], ARGV[1])`
+)
+
+func NewRedisStore(redisClient redis.UniversalClient, prekey string, defaultExpiration time.Duration) *RedisStore {
 	return &RedisStore{
 		redisClient: redisClient,
 		Prekey:      prekey,
@@ -33,13 +78,28 @@ func NewRedisStoreWithCfg(cfg *RedisConfig, preKey string, defaultExpiration tim
 	return NewRedisStore(redisClient.GetMasterDb(), preKey, defaultExpiration), nil
 }
 
+// UseWithContext sets the context used by the calls made with a nil context.
+//
+// Deprecated: pass the context to each method instead.
 func (rs *RedisStore) UseWithContext(ctx context.Context) *RedisStore {
 	rs.Context = ctx
 	return rs
 }
 
-func (rs *RedisStore) Set(key string, value interface{}, expires time.Duration) error {
-	err := rs.redisClient.Set(rs.Context, rs.Prekey+key, value, expires).Err()
+// ctxOf returns ctx, or the deprecated Context of the store when ctx is nil
+func (rs *RedisStore) ctxOf(ctx context.Context) context.Context {
+	if ctx != nil {
+		return ctx
+	}
+	if rs.Context != nil {
+		return rs.Context
+	}
+	return context.Background()
+}
+
+func (rs *RedisStore) Set(ctx context.Context, key string, value interface{}, expires time.Duration) error {
+	ctx = rs.ctxOf(ctx)
+	err := rs.redisClient.Set(ctx, rs.Prekey+key, value, rs.expiration(expires)).Err()
 	if err != nil {
 		fmt.Println(err)
 		return err
@@ -47,31 +107,49 @@ func (rs *RedisStore) Set(key string, value interface{}, expires time.Duration)
 	return nil
 }
 
-func (rs *RedisStore) Add(key string, value interface{}, expires time.Duration) error {
-	_, err := rs.redisClient.Get(rs.Context, rs.Prekey+key).Result()
-	if err == redis.Nil {
-		fmt.Println(key, " does not exist")
-		return cache.ErrNotStored
+// Add sets the item only if the key does not exist, ErrKeyExists is returned
+// otherwise
+func (rs *RedisStore) Add(ctx context.Context, key string, value interface{}, expires time.Duration) error {
+	ctx = rs.ctxOf(ctx)
+	added, err := rs.redisClient.SetNX(ctx, rs.Prekey+key, value, rs.expiration(expires)).Result()
+	if err != nil {
+		return err
 	}
-
-	return rs.Set(key, value, expires)
+	if !added {
+		return cache.ErrKeyExists
+	}
+	return nil
 }
 
-func (rs *RedisStore) Replace(key string, value interface{}, expires time.Duration) error {
-	return rs.Set(key, value, expires)
+// Replace sets the item only if the key exists, ErrNotStored is returned
+// otherwise
+func (rs *RedisStore) Replace(ctx context.Context, key string, value interface{}, expires time.Duration) error {
+	ctx = rs.ctxOf(ctx)
+	replaced, err := rs.redisClient.SetXX(ctx, rs.Prekey+key, value, rs.expiration(expires)).Result()
+	if err != nil {
+		return err
+	}
+	if !replaced {
+		return cache.ErrNotStored
+	}
+	return nil
 }
 
-func (rs *RedisStore) Get(key string) (string, error) {
-	value, err := rs.redisClient.Get(rs.Context, rs.Prekey+key).Result()
+func (rs *RedisStore) Get(ctx context.Context, key string) (string, error) {
+	ctx = rs.ctxOf(ctx)
+	value, err := rs.redisClient.Get(ctx, rs.Prekey+key).Result()
 	if err == redis.Nil {
-		fmt.Println(key, " does not exist")
-		return "", cache.ErrNotStored
+		return "", cache.ErrCacheMiss
+	}
+	if err != nil {
+		return "", err
 	}
 	return value, nil
 }
 
-func (rs *RedisStore) Delete(key string) error {
-	err := rs.redisClient.Del(rs.Context, rs.Prekey+key).Err()
+func (rs *RedisStore) Delete(ctx context.Context, key string) error {
+	ctx = rs.ctxOf(ctx)
+	err := rs.redisClient.Del(ctx, rs.Prekey+key).Err()
 	if err != nil {
 		fmt.Println(err)
 		return err
@@ -79,23 +157,237 @@ func (rs *RedisStore) Delete(key string) error {
 	return nil
 }
 
-func (rs *RedisStore) Increment(key string, value int64) (int64, error) {
-	newValue, err := rs.redisClient.IncrBy(rs.Context, rs.Prekey+key, value).Result()
+// Increment adds value to an existing integer item, ErrCacheMiss is returned
+// when the key does not exist
+func (rs *RedisStore) Increment(ctx context.Context, key string, value int64) (int64, error) {
+	ctx = rs.ctxOf(ctx)
+	newValue, err := rs.redisClient.Eval(ctx, incrementScript, []string{rs.Prekey + key}, value).Int64()
+	if err == redis.Nil {
+		return 0, cache.ErrCacheMiss
+	}
 	if err != nil {
 		return 0, err
 	}
 	return newValue, nil
 }
 
-func (rs *RedisStore) Decrement(key string, value int64) (int64, error) {
-	newValue, err := rs.redisClient.DecrBy(rs.Context, rs.Prekey+key, value).Result()
+// Decrement subtracts value from an existing integer item, the result may be
+// negative. ErrCacheMiss is returned when the key does not exist.
+func (rs *RedisStore) Decrement(ctx context.Context, key string, value int64) (int64, error) {
+	return rs.Increment(ctx, key, -value)
+}
+
+// Flush deletes every key of the store's namespace (Prekey*), keys of other
+// namespaces in the same Redis instance are left untouched. A store without
+// Prekey refuses to flush and returns ErrFlushWithoutPrefix.
+func (rs *RedisStore) Flush(ctx context.Context) error {
+	return rs.DeletePrefix(ctx, "")
+}
+
+func (rs *RedisStore) GetMulti(ctx context.Context, keys []string) (map[string]string, []string, error) {
+	ctx = rs.ctxOf(ctx)
+	if len(keys) == 0 {
+		return map[string]string{}, nil, nil
+	}
+	prekeys := make([]string, len(keys))
+	for i, key := range keys {
+		prekeys[i] = rs.Prekey + key
+	}
+
+	results, err := rs.mget(ctx, prekeys)
 	if err != nil {
-		return 0, err
+		return nil, nil, err
 	}
-	return newValue, nil
+
+	values := make(map[string]string, len(keys))
+	var misses []string
+	for i, result := range results {
+		value, ok := result.(string)
+		if !ok {
+			misses = append(misses, keys[i])
+			continue
+		}
+		values[keys[i]] = value
+	}
+	return values, misses, nil
+}
+
+func (rs *RedisStore) SetMulti(ctx context.Context, items map[string]interface{}, expires time.Duration) error {
+	ctx = rs.ctxOf(ctx)
+	if len(items) == 0 {
+		return nil
+	}
+	_, err := rs.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, value := range items {
+			pipe.Set(ctx, rs.Prekey+key, value, rs.expiration(expires))
+		}
+		return nil
+	})
+	return err
 }
 
-func (rs *RedisStore) Flush() error {
-	err := rs.redisClient.FlushAll(rs.Context).Err()
+func (rs *RedisStore) DeleteMulti(ctx context.Context, keys []string) error {
+	ctx = rs.ctxOf(ctx)
+	if len(keys) == 0 {
+		return nil
+	}
+	prekeys := make([]string, len(keys))
+	for i, key := range keys {
+		prekeys[i] = rs.Prekey + key
+	}
+	if rs.isCluster() {
+		return rs.unlink(ctx, prekeys)
+	}
+	return rs.redisClient.Del(ctx, prekeys...).Err()
+}
+
+// DeletePrefix removes every key of the store's namespace starting with prefix
+// using SCAN and UNLINK, so Redis is never blocked by a single huge command.
+// ErrFlushWithoutPrefix is returned when both Prekey and prefix are empty.
+func (rs *RedisStore) DeletePrefix(ctx context.Context, prefix string) error {
+	if rs.Prekey+prefix == "" {
+		return ErrFlushWithoutPrefix
+	}
+	ctx = rs.ctxOf(ctx)
+	pattern := escapeGlob(rs.Prekey+prefix) + "*"
+	if cluster, ok := rs.redisClient.(*redis.ClusterClient); ok {
+		// SCAN only covers one node, so every master is scanned separately
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return rs.deleteMatching(ctx, node, pattern)
+		})
+	}
+	return rs.deleteMatching(ctx, rs.redisClient, pattern)
+}
+
+// deleteMatching scans client for keys matching pattern and unlinks them in
+// batches
+func (rs *RedisStore) deleteMatching(ctx context.Context, client redis.UniversalClient, pattern string) error {
+	iter := client.Scan(ctx, 0, pattern, scanBatchSize).Iterator()
+	batch := make([]string, 0, scanBatchSize)
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) >= scanBatchSize {
+			if err := rs.unlink(ctx, batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	return rs.unlink(ctx, batch)
+}
+
+// SetWithTags sets an item and records its key under every tag
+func (rs *RedisStore) SetWithTags(ctx context.Context, key string, value interface{}, expires time.Duration, tags ...string) error {
+	ctx = rs.ctxOf(ctx)
+	expires = rs.expiration(expires)
+	_, err := rs.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, rs.Prekey+key, value, expires)
+		for _, tag := range tags {
+			pipe.Eval(ctx, tagScript, []string{rs.tagKey(tag)}, key, expires.Milliseconds())
+		}
+		return nil
+	})
 	return err
 }
+
+// InvalidateTags removes every key recorded under any of the tags
+func (rs *RedisStore) InvalidateTags(ctx context.Context, tags ...string) error {
+	ctx = rs.ctxOf(ctx)
+	for _, tag := range tags {
+		tagKey := rs.tagKey(tag)
+		members, err := rs.redisClient.SMembers(ctx, tagKey).Result()
+		if err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(members)+1)
+		for _, member := range members {
+			keys = append(keys, rs.Prekey+member)
+		}
+		keys = append(keys, tagKey)
+		for start := 0; start < len(keys); start += scanBatchSize {
+			end := min(start+scanBatchSize, len(keys))
+			if err := rs.unlink(ctx, keys[start:end]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isCluster reports whether the store runs against Redis Cluster, where multi
+// key commands fail when the keys hash to different slots
+func (rs *RedisStore) isCluster() bool {
+	_, ok := rs.redisClient.(*redis.ClusterClient)
+	return ok
+}
+
+// expiration maps cache.DEFAULT to the store's default expiration and
+// cache.FOREVER to no expiration
+func (rs *RedisStore) expiration(expires time.Duration) time.Duration {
+	switch expires {
+	case cache.DEFAULT:
+		return rs.Expiration
+	case cache.FOREVER:
+		return 0
+	}
+	return expires
+}
+
+// mget reads keys with MGET, or with a pipeline of GETs under cluster mode
+func (rs *RedisStore) mget(ctx context.Context, keys []string) ([]interface{}, error) {
+	if !rs.isCluster() {
+		return rs.redisClient.MGet(ctx, keys...).Result()
+	}
+	cmds := make([]*redis.StringCmd, len(keys))
+	_, err := rs.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Get(ctx, key)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	results := make([]interface{}, len(keys))
+	for i, cmd := range cmds {
+		if value, err := cmd.Result(); err == nil {
+			results[i] = value
+		}
+	}
+	return results, nil
+}
+
+func (rs *RedisStore) tagKey(tag string) string {
+	return rs.Prekey + "__tag:" + tag
+}
+
+// unlink removes keys without blocking Redis, one command per key so it also
+// works when keys live in different cluster slots
+func (rs *RedisStore) unlink(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := rs.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Unlink(ctx, key)
+		}
+		return nil
+	})
+	return err
+}
+
+// escapeGlob escapes the glob special characters of a SCAN MATCH pattern
+func escapeGlob(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,161 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/loongkirin/gdk/cache"
+	"github.com/loongkirin/gdk/cache/cachetest"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisStoreBatch(t *testing.T) {
+	ctx := context.Background()
+	_, client := newTestRedisClient(t)
+	store := NewRedisStore(client, "test:", 0)
+
+	err := store.SetMulti(ctx, map[string]interface{}{"a": "1", "b": "2"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, misses, err := store.GetMulti(ctx, []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["a"] != "1" || values["b"] != "2" || len(values) != 2 {
+		t.Errorf("unexpected values %v", values)
+	}
+	if len(misses) != 1 || misses[0] != "c" {
+		t.Errorf("unexpected misses %v", misses)
+	}
+
+	if err := store.DeleteMulti(ctx, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, misses, _ := store.GetMulti(ctx, []string{"a", "b"}); len(misses) != 2 {
+		t.Errorf("expected both keys to be deleted, misses %v", misses)
+	}
+
+	// 兼容旧调用方式：nil context 时使用 UseWithContext 设置的 context
+	if err := store.UseWithContext(ctx).Set(nil, "a", "1", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRedisStoreFlushIsNamespaced(t *testing.T) {
+	ctx := context.Background()
+	_, client := newTestRedisClient(t)
+	store := NewRedisStore(client, "test:", 0)
+	other := NewRedisStore(client, "other:", 0)
+
+	for _, key := range []string{"a", "b", "user:1", "user:2"} {
+		if err := store.Set(ctx, key, "v", time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := other.Set(ctx, "a", "v", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.DeletePrefix(ctx, "user:"); err != nil {
+		t.Fatal(err)
+	}
+	if _, misses, _ := store.GetMulti(ctx, []string{"a", "b", "user:1", "user:2"}); len(misses) != 2 {
+		t.Errorf("unexpected misses after DeletePrefix %v", misses)
+	}
+
+	if err := store.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, misses, _ := store.GetMulti(ctx, []string{"a", "b"}); len(misses) != 2 {
+		t.Errorf("unexpected misses after Flush %v", misses)
+	}
+	if v, err := other.Get(ctx, "a"); err != nil || v != "v" {
+		t.Errorf("other namespace was flushed: %q, %v", v, err)
+	}
+
+	unprefixed := NewRedisStore(client, "", 0)
+	if err := unprefixed.Flush(ctx); !errors.Is(err, ErrFlushWithoutPrefix) {
+		t.Errorf("flush without prekey got %v, want ErrFlushWithoutPrefix", err)
+	}
+	if err := unprefixed.DeletePrefix(ctx, ""); !errors.Is(err, ErrFlushWithoutPrefix) {
+		t.Errorf("delete of an empty prefix without prekey got %v, want ErrFlushWithoutPrefix", err)
+	}
+	if v, err := other.Get(ctx, "a"); err != nil || v != "v" {
+		t.Errorf("flush without prekey deleted keys: %q, %v", v, err)
+	}
+}
+
+func TestRedisStoreTags(t *testing.T) {
+	ctx := context.Background()
+	mr, client := newTestRedisClient(t)
+	store := NewRedisStore(client, "test:", 0)
+
+	if err := store.SetWithTags(ctx, "order:1", "v", time.Minute, "user:1", "orders"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetWithTags(ctx, "order:2", "v", 2*time.Minute, "user:2", "orders"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(ctx, "profile", "v", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := mr.TTL("test:__tag:orders"); ttl != 2*time.Minute {
+		t.Errorf("tag set ttl is %v, want 2m", ttl)
+	}
+
+	if err := store.InvalidateTags(ctx, "user:1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, misses, _ := store.GetMulti(ctx, []string{"order:1", "order:2", "profile"}); len(misses) != 1 || misses[0] != "order:1" {
+		t.Errorf("unexpected misses %v", misses)
+	}
+
+	if err := store.InvalidateTags(ctx, "orders"); err != nil {
+		t.Fatal(err)
+	}
+	if _, misses, _ := store.GetMulti(ctx, []string{"order:2", "profile"}); len(misses) != 1 || misses[0] != "order:2" {
+		t.Errorf("unexpected misses %v", misses)
+	}
+}
+
+// newRealtimeRedisClient starts a miniredis whose keys expire in real time,
+// miniredis only expires keys when its clock is moved forward
+func newRealtimeRedisClient(t *testing.T) *redis.Client {
+	mr, client := newTestRedisClient(t)
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				mr.FastForward(10 * time.Millisecond)
+			}
+		}
+	}()
+	return client
+}
+
+func TestRedisStoreConformance(t *testing.T) {
+	cachetest.Run(t, func(t *testing.T) cache.ContextCacheStore {
+		return NewRedisStore(newRealtimeRedisClient(t), "conformance:", 0)
+	})
+}
+
+func TestLayeredStoreConformance(t *testing.T) {
+	cachetest.Run(t, func(t *testing.T) cache.ContextCacheStore {
+		store, err := NewLayeredStore(context.Background(), NewRedisStore(newRealtimeRedisClient(t), "conformance:", 0), DefaultLayeredOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { store.Close() })
+		return store
+	})
+}
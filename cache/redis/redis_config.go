@@ -1,11 +1,27 @@
 package redis
 
+import "fmt"
+
+// Redis deployment modes used by RedisConfig.Mode, an empty mode is standalone
+const (
+	// RedisModeStandalone connects to a single master and optional static slaves
+	RedisModeStandalone = "standalone"
+	// RedisModeSentinel discovers the master and replicas through Sentinel
+	RedisModeSentinel = "sentinel"
+	// RedisModeCluster connects to a Redis Cluster
+	RedisModeCluster = "cluster"
+)
+
 type RedisConfig struct {
-	Master        RedisConnection   `mapstructure:"master" json:"master" yaml:"master"`
-	Slaves        []RedisConnection `mapstructure:"slaves" json:"slaves" yaml:"slaves"`
-	PoolSize      int               `mapstructure:"pool_size" json:"pool_size" yaml:"pool_size"`
-	EnableTracing bool              `mapstructure:"enable_tracing" json:"enable_tracing" yaml:"enable_tracing"`
-	EnableMetrics bool              `mapstructure:"enable_metrics" json:"enable_metrics" yaml:"enable_metrics"`
+	Mode          string                  `mapstructure:"mode" json:"mode" yaml:"mode"`
+	Master        RedisConnection         `mapstructure:"master" json:"master" yaml:"master"`
+	Slaves        []RedisConnection       `mapstructure:"slaves" json:"slaves" yaml:"slaves"`
+	Sentinel      RedisSentinelConfig     `mapstructure:"sentinel" json:"sentinel" yaml:"sentinel"`
+	Cluster       RedisClusterConfig      `mapstructure:"cluster" json:"cluster" yaml:"cluster"`
+	ReplicaProbe  RedisReplicaProbeConfig `mapstructure:"replica_probe" json:"replica_probe" yaml:"replica_probe"`
+	PoolSize      int                     `mapstructure:"pool_size" json:"pool_size" yaml:"pool_size"`
+	EnableTracing bool                    `mapstructure:"enable_tracing" json:"enable_tracing" yaml:"enable_tracing"`
+	EnableMetrics bool                    `mapstructure:"enable_metrics" json:"enable_metrics" yaml:"enable_metrics"`
 }
 
 type RedisConnection struct {
@@ -14,3 +30,53 @@ type RedisConnection struct {
 	Password string `mapstructure:"password" json:"password" yaml:"password"`
 	DB       int    `mapstructure:"db" json:"db" yaml:"db"`
 }
+
+// RedisSentinelConfig is used when Mode is sentinel
+type RedisSentinelConfig struct {
+	// MasterName is the name of the master monitored by the sentinels
+	MasterName string `mapstructure:"master_name" json:"master_name" yaml:"master_name"`
+	// Sentinels are the sentinel addresses, only Host and Port are used
+	Sentinels        []RedisConnection `mapstructure:"sentinels" json:"sentinels" yaml:"sentinels"`
+	SentinelPassword string            `mapstructure:"sentinel_password" json:"sentinel_password" yaml:"sentinel_password"`
+	Password         string            `mapstructure:"password" json:"password" yaml:"password"`
+	DB               int               `mapstructure:"db" json:"db" yaml:"db"`
+	// ReplicaRead routes GetSlaveDb to the replicas of the master. They are
+	// reached through one failover client, which picks a replica reported by
+	// Sentinel per connection, so they are probed as a whole: when the probed
+	// replica is unhealthy or lagging every read falls back to the master
+	// until the next probe, and no per-replica metrics are reported.
+	ReplicaRead bool `mapstructure:"replica_read" json:"replica_read" yaml:"replica_read"`
+}
+
+// RedisClusterConfig is used when Mode is cluster
+type RedisClusterConfig struct {
+	// Nodes are the seed nodes of the cluster, only Host and Port are used
+	Nodes    []RedisConnection `mapstructure:"nodes" json:"nodes" yaml:"nodes"`
+	Password string            `mapstructure:"password" json:"password" yaml:"password"`
+	// ReadOnly routes GetSlaveDb reads to replica nodes
+	ReadOnly bool `mapstructure:"read_only" json:"read_only" yaml:"read_only"`
+}
+
+func (c RedisConnection) addr() string {
+	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+}
+
+func addrs(conns []RedisConnection) []string {
+	result := make([]string, len(conns))
+	for i, conn := range conns {
+		result[i] = conn.addr()
+	}
+	return result
+}
+
+// RedisReplicaProbeConfig configures the background prober that takes
+// unhealthy or lagging slaves out of the GetSlaveDb rotation
+type RedisReplicaProbeConfig struct {
+	// Interval between two probes, defaults to 5s
+	Interval string `mapstructure:"interval" json:"interval" yaml:"interval"`
+	// Timeout of a single probe, defaults to 1s
+	Timeout string `mapstructure:"timeout" json:"timeout" yaml:"timeout"`
+	// MaxLag is the largest replication offset difference in bytes before a
+	// slave is considered lagging, defaults to 1MB, negative disables the check
+	MaxLag int64 `mapstructure:"max_lag" json:"max_lag" yaml:"max_lag"`
+}
@@ -0,0 +1,309 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/loongkirin/gdk/cache"
+	"github.com/loongkirin/gdk/cache/memeorycache"
+	"github.com/loongkirin/gdk/util"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	invalidateOpDelete = "del"
+	invalidateOpFlush  = "flush"
+)
+
+// LayeredOptions represents configuration options for LayeredStore
+type LayeredOptions struct {
+	// L1Expiration is the maximum time an item lives in the local memory cache
+	L1Expiration time.Duration
+	// L1CleanupInterval is the janitor interval of the local memory cache
+	L1CleanupInterval time.Duration
+	// Channel is the Redis pub/sub channel used to broadcast invalidations,
+	// defaults to Prekey + "__invalidate"
+	Channel string
+}
+
+// DefaultLayeredOptions returns the default options for LayeredStore
+func DefaultLayeredOptions() LayeredOptions {
+	return LayeredOptions{
+		L1Expiration:      time.Minute,
+		L1CleanupInterval: time.Minute,
+	}
+}
+
+// invalidateMessage is broadcast to every replica when keys change
+type invalidateMessage struct {
+	Origin string   `json:"origin"`
+	Op     string   `json:"op"`
+	Keys   []string `json:"keys,omitempty"`
+}
+
+// LayeredStore is a two-level cache: a local MemeoryCache (L1) in front of
+// a shared RedisStore (L2). Reads hit L1 first, then Redis, and populate L1 on
+// the way back. Every write and delete is broadcast over Redis pub/sub so all
+// replicas evict their L1 copy. L1Expiration bounds how long a replica can
+// serve a stale value if an invalidation is missed.
+type LayeredStore struct {
+	l2         *RedisStore
+	l1         *memeorycache.MemeoryCache
+	options    LayeredOptions
+	instanceId string
+	pubsub     *redis.PubSub
+	closeOnce  sync.Once
+	done       chan struct{}
+
+	// fetches are the Gets reading Redis, an eviction of their key marks
+	// them stale so they do not populate L1 with the value they read
+	mu      sync.Mutex
+	fetches map[*fetch]struct{}
+}
+
+// fetch is a Get reading key from Redis
+type fetch struct {
+	key   string
+	stale bool
+}
+
+var (
+	_ cache.ContextCacheStore = (*LayeredStore)(nil)
+	_ cache.TagCacheStore     = (*LayeredStore)(nil)
+	_ cache.PrefixCacheStore  = (*LayeredStore)(nil)
+)
+
+// NewLayeredStore creates a LayeredStore on top of store and starts listening
+// for invalidations from other replicas
+func NewLayeredStore(ctx context.Context, store *RedisStore, opts LayeredOptions) (*LayeredStore, error) {
+	if store == nil {
+		return nil, fmt.Errorf("redis store is required")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if opts.L1Expiration <= 0 {
+		opts.L1Expiration = DefaultLayeredOptions().L1Expiration
+	}
+	if opts.L1CleanupInterval <= 0 {
+		opts.L1CleanupInterval = DefaultLayeredOptions().L1CleanupInterval
+	}
+	if opts.Channel == "" {
+		opts.Channel = store.Prekey + "__invalidate"
+	}
+
+	pubsub := store.redisClient.Subscribe(ctx, opts.Channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe invalidation channel: %w", err)
+	}
+
+	ls := &LayeredStore{
+		l2:         store,
+		l1:         memeorycache.NewMemeoryCache(opts.L1Expiration, opts.L1CleanupInterval),
+		options:    opts,
+		instanceId: util.GenerateId(),
+		pubsub:     pubsub,
+		done:       make(chan struct{}),
+		fetches:    make(map[*fetch]struct{}),
+	}
+	go ls.listen()
+	return ls, nil
+}
+
+// listen evicts L1 entries as invalidations arrive from other replicas
+func (ls *LayeredStore) listen() {
+	defer close(ls.done)
+	for msg := range ls.pubsub.ChannelWithSubscriptions() {
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			// Invalidations published while we were disconnected are lost,
+			// so drop everything after a resubscribe.
+			if m.Kind == "subscribe" {
+				ls.evict(invalidateMessage{Op: invalidateOpFlush})
+			}
+		case *redis.Message:
+			var im invalidateMessage
+			if err := json.Unmarshal([]byte(m.Payload), &im); err != nil {
+				continue
+			}
+			if im.Origin == ls.instanceId {
+				continue
+			}
+			ls.evict(im)
+		}
+	}
+}
+
+func (ls *LayeredStore) evict(im invalidateMessage) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	switch im.Op {
+	case invalidateOpFlush:
+		ls.l1.Flush()
+		for f := range ls.fetches {
+			f.stale = true
+		}
+	case invalidateOpDelete:
+		for _, key := range im.Keys {
+			ls.l1.Delete(key)
+		}
+		for f := range ls.fetches {
+			f.stale = f.stale || slices.Contains(im.Keys, f.key)
+		}
+	}
+}
+
+// invalidate evicts keys locally and broadcasts the invalidation
+func (ls *LayeredStore) invalidate(ctx context.Context, op string, keys ...string) error {
+	im := invalidateMessage{
+		Origin: ls.instanceId,
+		Op:     op,
+		Keys:   keys,
+	}
+	ls.evict(im)
+
+	payload, err := json.Marshal(im)
+	if err != nil {
+		return err
+	}
+	if err := ls.l2.redisClient.Publish(ctx, ls.options.Channel, payload).Err(); err != nil {
+		return fmt.Errorf("failed to publish invalidation: %w", err)
+	}
+	return nil
+}
+
+// Get retrieves an item from L1, falling back to Redis
+func (ls *LayeredStore) Get(ctx context.Context, key string) (string, error) {
+	if v, found := ls.l1.Get(key); found {
+		return v.(string), nil
+	}
+
+	f := &fetch{key: key}
+	ls.mu.Lock()
+	ls.fetches[f] = struct{}{}
+	ls.mu.Unlock()
+	defer func() {
+		ls.mu.Lock()
+		delete(ls.fetches, f)
+		ls.mu.Unlock()
+	}()
+
+	pipe := ls.l2.redisClient.Pipeline()
+	getCmd := pipe.Get(ctx, ls.l2.Prekey+key)
+	ttlCmd := pipe.PTTL(ctx, ls.l2.Prekey+key)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return "", err
+	}
+
+	value, err := getCmd.Result()
+	if err == redis.Nil {
+		return "", cache.ErrCacheMiss
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// Never keep the L1 copy longer than Redis keeps the item
+	expiration := ls.options.L1Expiration
+	if ttl := ttlCmd.Val(); ttl > 0 && ttl < expiration {
+		expiration = ttl
+	}
+	// 读取 Redis 期间发生过失效时不写入 L1，避免缓存旧值
+	ls.mu.Lock()
+	if !f.stale {
+		ls.l1.Set(key, value, expiration)
+	}
+	ls.mu.Unlock()
+	return value, nil
+}
+
+func (ls *LayeredStore) Set(ctx context.Context, key string, value interface{}, expires time.Duration) error {
+	if err := ls.l2.Set(ctx, key, value, expires); err != nil {
+		return err
+	}
+	return ls.invalidate(ctx, invalidateOpDelete, key)
+}
+
+func (ls *LayeredStore) Add(ctx context.Context, key string, value interface{}, expires time.Duration) error {
+	if err := ls.l2.Add(ctx, key, value, expires); err != nil {
+		return err
+	}
+	return ls.invalidate(ctx, invalidateOpDelete, key)
+}
+
+func (ls *LayeredStore) Replace(ctx context.Context, key string, value interface{}, expires time.Duration) error {
+	if err := ls.l2.Replace(ctx, key, value, expires); err != nil {
+		return err
+	}
+	return ls.invalidate(ctx, invalidateOpDelete, key)
+}
+
+func (ls *LayeredStore) Delete(ctx context.Context, key string) error {
+	if err := ls.l2.Delete(ctx, key); err != nil {
+		return err
+	}
+	return ls.invalidate(ctx, invalidateOpDelete, key)
+}
+
+func (ls *LayeredStore) Increment(ctx context.Context, key string, value int64) (int64, error) {
+	newValue, err := ls.l2.Increment(ctx, key, value)
+	if err != nil {
+		return 0, err
+	}
+	return newValue, ls.invalidate(ctx, invalidateOpDelete, key)
+}
+
+func (ls *LayeredStore) Decrement(ctx context.Context, key string, value int64) (int64, error) {
+	newValue, err := ls.l2.Decrement(ctx, key, value)
+	if err != nil {
+		return 0, err
+	}
+	return newValue, ls.invalidate(ctx, invalidateOpDelete, key)
+}
+
+func (ls *LayeredStore) Flush(ctx context.Context) error {
+	if err := ls.l2.Flush(ctx); err != nil {
+		return err
+	}
+	return ls.invalidate(ctx, invalidateOpFlush)
+}
+
+func (ls *LayeredStore) SetWithTags(ctx context.Context, key string, value interface{}, expires time.Duration, tags ...string) error {
+	if err := ls.l2.SetWithTags(ctx, key, value, expires, tags...); err != nil {
+		return err
+	}
+	return ls.invalidate(ctx, invalidateOpDelete, key)
+}
+
+// InvalidateTags removes the tagged items from Redis. The affected keys are
+// not known locally, so every replica drops its whole L1.
+func (ls *LayeredStore) InvalidateTags(ctx context.Context, tags ...string) error {
+	if err := ls.l2.InvalidateTags(ctx, tags...); err != nil {
+		return err
+	}
+	return ls.invalidate(ctx, invalidateOpFlush)
+}
+
+// DeletePrefix removes the matching items from Redis and every replica's L1
+func (ls *LayeredStore) DeletePrefix(ctx context.Context, prefix string) error {
+	if err := ls.l2.DeletePrefix(ctx, prefix); err != nil {
+		return err
+	}
+	return ls.invalidate(ctx, invalidateOpFlush)
+}
+
+// Close stops listening for invalidations
+func (ls *LayeredStore) Close() error {
+	var err error
+	ls.closeOnce.Do(func() {
+		err = ls.pubsub.Close()
+		<-ls.done
+		ls.l1.Flush()
+	})
+	return err
+}
@@ -0,0 +1,125 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedisClient(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return mr, client
+}
+
+func TestLayeredStoreInvalidation(t *testing.T) {
+	ctx := context.Background()
+	_, client := newTestRedisClient(t)
+
+	replicaA, err := NewLayeredStore(ctx, NewRedisStore(client, "test:", 0), DefaultLayeredOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replicaA.Close()
+	replicaB, err := NewLayeredStore(ctx, NewRedisStore(client, "test:", 0), DefaultLayeredOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replicaB.Close()
+
+	if err := replicaA.Set(ctx, "k", "v1", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	// 在途的失效消息会让读取跳过 L1，重试直到写入
+	deadline := time.Now().Add(time.Second)
+	for {
+		if v, err := replicaB.Get(ctx, "k"); err != nil || v != "v1" {
+			t.Fatalf("replica B got %q, %v", v, err)
+		}
+		if _, found := replicaB.l1.Get("k"); found {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("replica B should have populated L1")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := replicaA.Set(ctx, "k", "v2", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	deadline = time.Now().Add(time.Second)
+	for {
+		if _, found := replicaB.l1.Get("k"); !found {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("replica B L1 entry was not invalidated")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if v, err := replicaB.Get(ctx, "k"); err != nil || v != "v2" {
+		t.Fatalf("replica B got %q, %v", v, err)
+	}
+}
+
+// afterPipelineHook runs fn once after the next pipeline
+type afterPipelineHook struct {
+	fn *func()
+}
+
+func (h afterPipelineHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h afterPipelineHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return next
+}
+
+func (h afterPipelineHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+		if fn := *h.fn; fn != nil {
+			*h.fn = nil
+			fn()
+		}
+		return err
+	}
+}
+
+func TestLayeredStoreSkipsL1AfterConcurrentInvalidation(t *testing.T) {
+	ctx := context.Background()
+	_, client := newTestRedisClient(t)
+	var afterRead func()
+	client.AddHook(afterPipelineHook{fn: &afterRead})
+
+	ls, err := NewLayeredStore(ctx, NewRedisStore(client, "test:", 0), DefaultLayeredOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ls.Close()
+	if err := ls.Set(ctx, "k", "v1", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	// 在 Get 读取 Redis 之后、写入 L1 之前修改该键
+	afterRead = func() {
+		if err := ls.Set(ctx, "k", "v2", time.Minute); err != nil {
+			t.Error(err)
+		}
+	}
+	if v, err := ls.Get(ctx, "k"); err != nil || v != "v1" {
+		t.Fatalf("got %q, %v", v, err)
+	}
+	if _, found := ls.l1.Get("k"); found {
+		t.Fatal("stale value was cached in L1")
+	}
+	if v, err := ls.Get(ctx, "k"); err != nil || v != "v2" {
+		t.Fatalf("got %q, %v", v, err)
+	}
+}
@@ -7,22 +7,16 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/loongkirin/gdk/cache"
+	"github.com/loongkirin/gdk/lock"
+	"github.com/loongkirin/gdk/util"
 	"github.com/redis/go-redis/v9"
 )
 
-// Locker defines the interface for a distributed lock
-type Locker interface {
-	// Lock attempts to acquire the lock
-	Lock(ctx context.Context) error
-	// LockWithRetry attempts to acquire the lock with exponential backoff
-	LockWithRetry(ctx context.Context, maxRetries int, initialDelay time.Duration) error
-	// Unlock releases the lock if it is still held
-	Unlock(ctx context.Context) error
-	// Refresh extends the lock duration
-	Refresh(ctx context.Context) error
-	// Close releases any resources held by the lock
-	Close(ctx context.Context) error
-}
+// Locker defines the interface for a distributed lock.
+//
+// Deprecated: use lock.Locker.
+type Locker = lock.Locker
 
 // Lua scripts for atomic operations
 const (
@@ -34,17 +28,30 @@ const (
 			return 0
 		end`
 
+	// 读取锁的持有者，可重入锁以 hash 存储
+	lockOwnerScript = `
+		local function owner(key)
+			if redis.call("type", key).ok == "hash" then
+				return redis.call("hget", key, "owner")
+			end
+			return redis.call("get", key)
+		end`
+
 	// 刷新脚本：检查并更新过期时间
-	refreshScript = `
-		if redis.call("get", KEYS[1]) == ARGV[1] then
+	refreshScript = lockOwnerScript + `
+		if owner(KEYS[1]) == ARGV[1] then
 			return redis.call("pexpire", KEYS[1], ARGV[2])
 		else
 			return 0
 		end`
 
+	// 持有者检查脚本：锁可能被另一种类型（字符串或 hash）的锁持有
+	ownerScript = lockOwnerScript + `
+		return owner(KEYS[1])`
+
 	// 健康检查脚本：检查锁的状态和剩余时间
-	healthCheckScript = `
-		local value = redis.call("get", KEYS[1])
+	healthCheckScript = lockOwnerScript + `
+		local value = owner(KEYS[1])
 		if value == false then
 			return {0, 0, 0}  -- key doesn't exist
 		end
@@ -54,6 +61,55 @@ const (
 		end
 		return {1, ttl, 0}  -- owned by others
 	`
+
+	// 加锁脚本：支持可重入和栅栏令牌
+	// KEYS[1] 锁, KEYS[2] 栅栏令牌计数器（仅启用栅栏令牌时传入）
+	// ARGV[1] 持有者, ARGV[2] 过期毫秒数, ARGV[3] 是否可重入, ARGV[4] 是否启用栅栏令牌
+	// 返回 {是否获得, 持有次数, 栅栏令牌}
+	lockScript = `
+		local function nextToken()
+			if ARGV[4] == "1" then
+				return redis.call("incr", KEYS[2])
+			end
+			return 0
+		end
+
+		if ARGV[3] == "1" then
+			if redis.call("exists", KEYS[1]) == 1 then
+				if redis.call("type", KEYS[1]).ok ~= "hash" or redis.call("hget", KEYS[1], "owner") ~= ARGV[1] then
+					return {0, 0, 0}
+				end
+				local holds = redis.call("hincrby", KEYS[1], "holds", 1)
+				redis.call("pexpire", KEYS[1], ARGV[2])
+				return {1, holds, tonumber(redis.call("hget", KEYS[1], "token"))}
+			end
+			local token = nextToken()
+			redis.call("hset", KEYS[1], "owner", ARGV[1], "holds", 1, "token", token)
+			redis.call("pexpire", KEYS[1], ARGV[2])
+			return {1, 1, token}
+		end
+
+		if not redis.call("set", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+			return {0, 0, 0}
+		end
+		return {1, 1, nextToken()}`
+
+	// 可重入解锁脚本：减少持有次数，归零时删除锁
+	// 返回剩余持有次数，未持有时返回 -1
+	reentrantUnlockScript = lockOwnerScript + `
+		if owner(KEYS[1]) ~= ARGV[1] then
+			return -1
+		end
+		if redis.call("type", KEYS[1]).ok ~= "hash" then
+			redis.call("del", KEYS[1])
+			return 0
+		end
+		local holds = redis.call("hincrby", KEYS[1], "holds", -1)
+		if holds <= 0 then
+			redis.call("del", KEYS[1])
+			return 0
+		end
+		return holds`
 )
 
 // LockState represents the current state of the lock
@@ -68,21 +124,22 @@ const (
 	LockStateExpired
 )
 
-// Common errors that can be returned by the RedisLock
+// Common errors that can be returned by the RedisLock, they are the errors
+// of the lock package so callers can handle every Locker the same way
 var (
-	ErrLockNotObtained   = errors.New("lock not obtained")
-	ErrLockNotHeld       = errors.New("lock not held")
-	ErrInvalidContext    = errors.New("invalid context")
-	ErrLockExpired       = errors.New("lock has expired")
-	ErrAlreadyLocked     = errors.New("lock is already held")
-	ErrLockKeyRequired   = errors.New("lock key is required")
-	ErrLockValueRequired = errors.New("lock value is required")
+	ErrLockNotObtained   = lock.ErrLockNotObtained
+	ErrLockNotHeld       = lock.ErrLockNotHeld
+	ErrInvalidContext    = lock.ErrInvalidContext
+	ErrLockExpired       = lock.ErrLockExpired
+	ErrAlreadyLocked     = lock.ErrAlreadyLocked
+	ErrLockKeyRequired   = lock.ErrLockKeyRequired
+	ErrLockValueRequired = lock.ErrLockValueRequired
 )
 
 // RedisLock represents a distributed lock using Redis
 type RedisLock struct {
 	// client is the Redis client instance
-	client *redis.Client
+	client redis.UniversalClient
 	// key is the Redis key used for the lock
 	key string
 	// value is the unique identifier for this lock instance
@@ -90,6 +147,12 @@ type RedisLock struct {
 	// expiration is the lock's time-to-live duration
 	expiration time.Duration
 	state      int32 // atomic
+	// reentrant allows the same owner value to acquire the lock repeatedly
+	reentrant bool
+	// fencing makes Lock increment a fencing token counter
+	fencing bool
+	holds   int64 // atomic
+	token   int64 // atomic
 }
 
 // LockHealth represents the health status of a lock
@@ -101,7 +164,7 @@ type LockHealth struct {
 }
 
 // NewRedisLock creates a new Redis-based distributed lock
-func NewRedisLock(client *redis.Client, key, value string, expiration time.Duration) (*RedisLock, error) {
+func NewRedisLock(client redis.UniversalClient, key, value string, expiration time.Duration) (*RedisLock, error) {
 	if client == nil {
 		return nil, errors.New("redis client is required")
 	}
@@ -126,38 +189,139 @@ func NewRedisLock(client *redis.Client, key, value string, expiration time.Durat
 	}, nil
 }
 
+// NewLockFactory returns a cache.LockFactory that creates RedisLocks with a
+// unique value, so cache.Loader can collapse misses across replicas
+func NewLockFactory(client redis.UniversalClient, expiration time.Duration) cache.LockFactory {
+	factory := NewLockerFactory(client)
+	return func(key string) (cache.Lock, error) {
+		return factory(key, expiration)
+	}
+}
+
+// NewLockerFactory returns a lock.Factory that creates RedisLocks with a
+// unique value, for components that accept any Locker
+func NewLockerFactory(client redis.UniversalClient) lock.Factory {
+	return func(key string, expiration time.Duration) (lock.Locker, error) {
+		if client == nil {
+			return nil, errors.New("redis client is required")
+		}
+		if key == "" {
+			return nil, ErrLockKeyRequired
+		}
+		if expiration <= 0 {
+			return nil, errors.New("expiration must be positive")
+		}
+		return &RedisLock{
+			client:     client,
+			key:        key,
+			value:      util.GenerateId(),
+			expiration: expiration,
+		}, nil
+	}
+}
+
 // Lock attempts to acquire the lock
 func (l *RedisLock) Lock(ctx context.Context) error {
+	_, err := l.LockWithToken(ctx)
+	return err
+}
+
+// LockWithToken attempts to acquire the lock and returns its fencing token.
+// The token increases with every new acquisition of the key, so downstream
+// storage can reject writes carrying an older token. It is zero unless the
+// lock is created with Options.Fencing. A reentrant acquisition returns the
+// token of the first one.
+func (l *RedisLock) LockWithToken(ctx context.Context) (int64, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	// Check if already locked
-	if !atomic.CompareAndSwapInt32(&l.state, int32(LockStateUnlocked), int32(LockStateLocked)) {
-		return ErrAlreadyLocked
+	// Check if already locked, a lock that expired may be acquired again
+	if !l.reentrant && !l.claim() {
+		// 租约过期后本地状态仍为已锁定，锁已不属于自己时允许重新获取
+		held, err := l.IsHeldByMe(ctx)
+		if err != nil {
+			return 0, err
+		}
+		if held || !atomic.CompareAndSwapInt32(&l.state, int32(LockStateLocked), int32(LockStateExpired)) || !l.claim() {
+			return 0, ErrAlreadyLocked
+		}
 	}
 
-	ok, err := l.client.SetNX(ctx, l.key, l.value, l.expiration).Result()
+	if !l.reentrant && !l.fencing {
+		ok, err := l.client.SetNX(ctx, l.key, l.value, l.expiration).Result()
 
-	if err != nil {
-		atomic.StoreInt32(&l.state, int32(LockStateUnlocked))
-		return fmt.Errorf("failed to acquire lock: %w", err)
+		if err != nil {
+			atomic.StoreInt32(&l.state, int32(LockStateUnlocked))
+			return 0, fmt.Errorf("failed to acquire lock: %w", err)
+		}
+
+		if !ok {
+			atomic.StoreInt32(&l.state, int32(LockStateUnlocked))
+			return 0, ErrLockNotObtained
+		}
+
+		atomic.StoreInt64(&l.holds, 1)
+		return 0, nil
 	}
 
-	if !ok {
-		atomic.StoreInt32(&l.state, int32(LockStateUnlocked))
-		return ErrLockNotObtained
+	keys := []string{l.key}
+	if l.fencing {
+		keys = append(keys, l.FencingKey())
+	}
+	result, err := l.client.Eval(ctx, lockScript, keys, l.value, l.expiration.Milliseconds(), flag(l.reentrant), flag(l.fencing)).Int64Slice()
+	if err != nil {
+		if !l.reentrant {
+			atomic.StoreInt32(&l.state, int32(LockStateUnlocked))
+		}
+		return 0, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	if len(result) != 3 || result[0] == 0 {
+		if !l.reentrant {
+			atomic.StoreInt32(&l.state, int32(LockStateUnlocked))
+		}
+		return 0, ErrLockNotObtained
 	}
 
-	return nil
+	atomic.StoreInt32(&l.state, int32(LockStateLocked))
+	atomic.StoreInt64(&l.holds, result[1])
+	atomic.StoreInt64(&l.token, result[2])
+	return result[2], nil
 }
 
-// Unlock releases the lock if it is still held by the current instance
+// claim moves the local state of an unlocked or expired lock to locked
+func (l *RedisLock) claim() bool {
+	return atomic.CompareAndSwapInt32(&l.state, int32(LockStateUnlocked), int32(LockStateLocked)) ||
+		atomic.CompareAndSwapInt32(&l.state, int32(LockStateExpired), int32(LockStateLocked))
+}
+
+// Unlock releases the lock if it is still held by the current instance. A
+// reentrant lock is only released when every acquisition has been unlocked.
 func (l *RedisLock) Unlock(ctx context.Context) error {
 	if ctx == nil {
 		return ErrInvalidContext
 	}
 
+	if l.reentrant {
+		if LockState(atomic.LoadInt32(&l.state)) != LockStateLocked {
+			return ErrLockNotHeld
+		}
+		holds, err := l.client.Eval(ctx, reentrantUnlockScript, []string{l.key}, l.value).Int64()
+		if err != nil {
+			return fmt.Errorf("failed to release lock: %w", err)
+		}
+		if holds < 0 {
+			atomic.StoreInt64(&l.holds, 0)
+			atomic.StoreInt32(&l.state, int32(LockStateExpired))
+			return ErrLockNotHeld
+		}
+		atomic.StoreInt64(&l.holds, holds)
+		if holds == 0 {
+			atomic.StoreInt32(&l.state, int32(LockStateUnlocked))
+		}
+		return nil
+	}
+
 	if !atomic.CompareAndSwapInt32(&l.state, int32(LockStateLocked), int32(LockStateUnlocked)) {
 		return ErrLockNotHeld
 	}
@@ -169,6 +333,7 @@ func (l *RedisLock) Unlock(ctx context.Context) error {
 		return fmt.Errorf("failed to release lock: %w", err)
 	}
 
+	atomic.StoreInt64(&l.holds, 0)
 	if result.(int64) == 0 {
 		atomic.StoreInt32(&l.state, int32(LockStateExpired))
 		return ErrLockNotHeld
@@ -177,6 +342,36 @@ func (l *RedisLock) Unlock(ctx context.Context) error {
 	return nil
 }
 
+// reset forgets the local state of the lock, the key is left to expire
+func (l *RedisLock) reset() {
+	atomic.StoreInt64(&l.holds, 0)
+	atomic.StoreInt32(&l.state, int32(LockStateUnlocked))
+}
+
+// Token returns the fencing token of the last acquisition
+func (l *RedisLock) Token() int64 {
+	return atomic.LoadInt64(&l.token)
+}
+
+// Holds returns how many times the owner holds the lock as of the last Lock
+// or Unlock of this instance
+func (l *RedisLock) Holds() int {
+	return int(atomic.LoadInt64(&l.holds))
+}
+
+// FencingKey returns the key of the fencing token counter. It is hash tagged
+// like the lock key, so both live in the same Redis Cluster slot.
+func (l *RedisLock) FencingKey() string {
+	return slotKey(l.key, ":fencing")
+}
+
+func flag(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
 // Refresh extends the lock duration if it is still held by the current instance
 func (l *RedisLock) Refresh(ctx context.Context) error {
 	if ctx == nil {
@@ -218,7 +413,8 @@ func (l *RedisLock) IsHeldByMe(ctx context.Context) (bool, error) {
 		ctx = context.Background()
 	}
 
-	value, err := l.client.Get(ctx, l.key).Result()
+	// 在脚本中按类型读取持有者，被另一种锁持有时不会返回 WRONGTYPE
+	value, err := l.client.Eval(ctx, ownerScript, []string{l.key}).Text()
 	if err == redis.Nil {
 		return false, nil
 	}
@@ -230,74 +426,16 @@ func (l *RedisLock) IsHeldByMe(ctx context.Context) (bool, error) {
 
 // LockWithRetry attempts to acquire the lock with exponential backoff
 func (l *RedisLock) LockWithRetry(ctx context.Context, maxRetries int, initialDelay time.Duration) error {
-	if ctx == nil {
-		return ErrInvalidContext
-	}
-
-	backoff := &ExponentialBackoff{
-		InitialDelay: initialDelay,
-		MaxDelay:     initialDelay * 10,
-		Factor:       2,
-	}
-
-	for i := 0; i <= maxRetries; i++ {
-		err := l.Lock(ctx)
-		if err == nil {
-			return nil
-		}
-		if err != ErrLockNotObtained {
-			return err
-		}
-		if i == maxRetries {
-			return ErrLockNotObtained
-		}
-
-		delay := backoff.NextBackoff()
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(delay):
-			// Continue with next retry
-		}
-	}
-	return ErrLockNotObtained
+	return lock.LockWithRetry(ctx, maxRetries, initialDelay, l.Lock)
 }
 
 // AutoRefresh starts a goroutine to automatically refresh the lock
 // It returns a channel that will receive refresh errors and a stop function
 func (l *RedisLock) AutoRefresh(ctx context.Context, refreshInterval time.Duration) (chan error, func()) {
-	if ctx == nil {
-		ctx = context.Background()
-	}
 	if refreshInterval <= 0 {
 		refreshInterval = l.expiration / 3
 	}
-
-	resultCh := make(chan error, 1)
-	stopCh := make(chan struct{})
-
-	go func() {
-		ticker := time.NewTicker(refreshInterval)
-		defer ticker.Stop()
-		defer close(resultCh)
-
-		for {
-			select {
-			case <-ctx.Done():
-				resultCh <- ctx.Err()
-				return
-			case <-stopCh:
-				return
-			case <-ticker.C:
-				if err := l.Refresh(ctx); err != nil {
-					resultCh <- err
-					return
-				}
-			}
-		}
-	}()
-
-	return resultCh, func() { close(stopCh) }
+	return lock.AutoRefresh(ctx, refreshInterval, l.Refresh)
 }
 
 // Close implements io.Closer and releases any resources
@@ -340,6 +478,11 @@ type Options struct {
 	RetryDelay time.Duration
 	// RefreshInterval is the interval for auto-refresh
 	RefreshInterval time.Duration
+	// Reentrant allows the same owner value to acquire the lock repeatedly,
+	// the lock is released when every acquisition has been unlocked
+	Reentrant bool
+	// Fencing makes Lock return a monotonically increasing fencing token
+	Fencing bool
 }
 
 // DefaultOptions returns the default options for RedisLock
@@ -362,30 +505,13 @@ func (l *RedisLock) WithOptions(opts Options) *RedisLock {
 	if opts.RefreshInterval <= 0 {
 		opts.RefreshInterval = DefaultOptions().RefreshInterval
 	}
+	l.reentrant = opts.Reentrant
+	l.fencing = opts.Fencing
 	return l
 }
 
 // ExponentialBackoff implements exponential backoff algorithm
-type ExponentialBackoff struct {
-	InitialDelay time.Duration
-	MaxDelay     time.Duration
-	Factor       float64
-	current      time.Duration
-}
-
-// NextBackoff returns the next backoff delay
-func (b *ExponentialBackoff) NextBackoff() time.Duration {
-	if b.current == 0 {
-		b.current = b.InitialDelay
-		return b.current
-	}
-
-	b.current = time.Duration(float64(b.current) * b.Factor)
-	if b.current > b.MaxDelay {
-		b.current = b.MaxDelay
-	}
-	return b.current
-}
+type ExponentialBackoff = lock.ExponentialBackoff
 
 // LockWithContext attempts to acquire the lock with a context deadline
 func (l *RedisLock) LockWithContext(ctx context.Context) error {
@@ -479,6 +605,7 @@ func (l *RedisLock) Cleanup(ctx context.Context) error {
 
 	// 重置状态
 	atomic.StoreInt32(&l.state, int32(LockStateUnlocked))
+	atomic.StoreInt64(&l.holds, 0)
 	return nil
 }
 
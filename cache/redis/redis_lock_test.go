@@ -0,0 +1,135 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRedisLockFencingToken(t *testing.T) {
+	ctx := context.Background()
+	_, client := newTestRedisClient(t)
+
+	first, _ := NewRedisLock(client, "lock", "first", time.Minute)
+	first.WithOptions(Options{Fencing: true})
+	second, _ := NewRedisLock(client, "lock", "second", time.Minute)
+	second.WithOptions(Options{Fencing: true})
+
+	token1, err := first.LockWithToken(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.LockWithToken(ctx); !errors.Is(err, ErrLockNotObtained) {
+		t.Fatalf("second owner got %v, want ErrLockNotObtained", err)
+	}
+	if err := first.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	token2, err := second.LockWithToken(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token1 <= 0 || token2 <= token1 {
+		t.Errorf("tokens %d, %d are not increasing", token1, token2)
+	}
+	if second.Token() != token2 {
+		t.Errorf("Token() = %d, want %d", second.Token(), token2)
+	}
+	// 栅栏令牌计数器与锁在同一个 cluster slot
+	if key := first.FencingKey(); key != "{lock}:fencing" {
+		t.Errorf("FencingKey() = %q", key)
+	}
+	tagged, _ := NewRedisLock(client, "{order:1}:lock", "owner", time.Minute)
+	if key := tagged.FencingKey(); key != "{order:1}:lock:fencing" {
+		t.Errorf("FencingKey() of a hash tagged key = %q", key)
+	}
+}
+
+func TestRedisLockReentrant(t *testing.T) {
+	ctx := context.Background()
+	mr, client := newTestRedisClient(t)
+
+	opts := Options{Reentrant: true, Fencing: true}
+	lock, _ := NewRedisLock(client, "lock", "owner", time.Minute)
+	lock.WithOptions(opts)
+	sameOwner, _ := NewRedisLock(client, "lock", "owner", time.Minute)
+	sameOwner.WithOptions(opts)
+	other, _ := NewRedisLock(client, "lock", "other", time.Minute)
+	other.WithOptions(opts)
+
+	token, err := lock.LockWithToken(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	reentrantToken, err := sameOwner.LockWithToken(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reentrantToken != token || sameOwner.Holds() != 3 {
+		t.Errorf("got token %d with %d holds, want token %d with 3 holds", reentrantToken, sameOwner.Holds(), token)
+	}
+	if err := other.Lock(ctx); !errors.Is(err, ErrLockNotObtained) {
+		t.Fatalf("other owner got %v, want ErrLockNotObtained", err)
+	}
+	if held, err := lock.IsHeldByMe(ctx); err != nil || !held {
+		t.Errorf("IsHeldByMe = %v, %v", held, err)
+	}
+	plain, _ := NewRedisLock(client, "lock", "plain", time.Minute)
+	if held, err := plain.IsHeldByMe(ctx); err != nil || held {
+		t.Errorf("plain lock IsHeldByMe of a reentrant lock = %v, %v", held, err)
+	}
+	if err := lock.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := lock.Unlock(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !mr.Exists("lock") {
+		t.Fatal("lock released before every hold was unlocked")
+	}
+	if err := sameOwner.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists("lock") {
+		t.Fatal("lock not released after the last unlock")
+	}
+	if err := other.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 字符串锁持有 key 时，可重入锁的检查返回 false 而不是 WRONGTYPE
+	if err := other.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := plain.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if held, err := other.IsHeldByMe(ctx); err != nil || held {
+		t.Errorf("reentrant IsHeldByMe of a plain lock = %v, %v", held, err)
+	}
+}
+
+func TestRedisLockReacquiresAfterExpiry(t *testing.T) {
+	ctx := context.Background()
+	mr, client := newTestRedisClient(t)
+	lock, _ := NewRedisLock(client, "lock", "owner", time.Second)
+
+	if err := lock.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// 租约过期后未释放，同一实例可以重新获得锁
+	mr.FastForward(2 * time.Second)
+	if err := lock.Lock(ctx); err != nil {
+		t.Fatalf("Lock after expiry got %v", err)
+	}
+	if err := lock.Lock(ctx); !errors.Is(err, ErrAlreadyLocked) {
+		t.Errorf("second Lock got %v, want ErrAlreadyLocked", err)
+	}
+}
@@ -0,0 +1,244 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loongkirin/gdk/util"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	defaultReplicaProbeInterval = 5 * time.Second
+	defaultReplicaProbeTimeout  = time.Second
+	defaultMaxReplicaLag        = 1 << 20
+)
+
+var errReplicaLinkDown = errors.New("replica link to master is down")
+
+// ReplicaState represents the routing state of a slave
+type ReplicaState int32
+
+const (
+	// ReplicaHealthy indicates the slave is in the GetSlaveDb rotation
+	ReplicaHealthy ReplicaState = iota
+	// ReplicaLagging indicates the slave responds but is too far behind the master
+	ReplicaLagging
+	// ReplicaUnhealthy indicates the slave does not respond or lost its master
+	ReplicaUnhealthy
+)
+
+func (s ReplicaState) String() string {
+	switch s {
+	case ReplicaHealthy:
+		return "healthy"
+	case ReplicaLagging:
+		return "lagging"
+	case ReplicaUnhealthy:
+		return "unhealthy"
+	default:
+		return "unknown"
+	}
+}
+
+// ReplicaStatus represents the result of the last probe of a slave
+type ReplicaStatus struct {
+	Name      string       // slave_<index>，sentinel 模式下为 replicas
+	State     ReplicaState // 路由状态
+	Lag       int64        // 复制偏移量差值，未知时为 -1
+	LastCheck time.Time    // 最后检查时间
+	Err       error        // 最后一次探测的错误
+}
+
+// options returns the probe settings, invalid or empty values fall back to
+// the defaults
+func (cfg RedisReplicaProbeConfig) options() (interval, timeout time.Duration, maxLag int64) {
+	interval, timeout, maxLag = defaultReplicaProbeInterval, defaultReplicaProbeTimeout, cfg.MaxLag
+	if duration, err := util.ParseDuration(cfg.Interval); err == nil && duration > 0 {
+		interval = duration
+	}
+	if duration, err := util.ParseDuration(cfg.Timeout); err == nil && duration > 0 {
+		timeout = duration
+	}
+	if maxLag == 0 {
+		maxLag = defaultMaxReplicaLag
+	}
+	return interval, timeout, maxLag
+}
+
+// startReplicaProbe runs the prober until Close is called. Slaves of a cluster
+// are routed by the cluster client itself and are not probed. In sentinel
+// mode the replicas sit behind a single failover client that picks one per
+// connection, so the prober keeps one aggregate state for all of them.
+func (r *RedisClient) startReplicaProbe() {
+	r.replicas = make([]ReplicaStatus, len(r.slaves))
+	for i := range r.replicas {
+		name := fmt.Sprintf("slave_%d", i)
+		if r.RedisConfig.Mode == RedisModeSentinel {
+			name = "replicas"
+		}
+		r.replicas[i] = ReplicaStatus{Name: name, State: ReplicaHealthy, Lag: -1}
+	}
+	r.stopProbe = make(chan struct{})
+	r.probeDone = make(chan struct{})
+	if len(r.slaves) == 0 || r.RedisConfig.Mode == RedisModeCluster {
+		close(r.probeDone)
+		return
+	}
+
+	interval, timeout, maxLag := r.RedisConfig.ReplicaProbe.options()
+	go func() {
+		defer close(r.probeDone)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.stopProbe:
+				return
+			case <-ticker.C:
+				r.probeReplicas(timeout, maxLag)
+			}
+		}
+	}()
+}
+
+// stopReplicaProbe stops the prober and waits for it to exit
+func (r *RedisClient) stopReplicaProbe() {
+	if r.stopProbe == nil {
+		return
+	}
+	r.stopOnce.Do(func() { close(r.stopProbe) })
+	<-r.probeDone
+}
+
+// probeReplicas updates the state of every slave. The slaves are probed
+// concurrently, each call having its own timeout, so slow nodes do not eat
+// the deadline of the others.
+func (r *RedisClient) probeReplicas(timeout time.Duration, maxLag int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	masterOffset, masterErr := replicationOffset(ctx, r.master, "master_repl_offset")
+	cancel()
+
+	var wg sync.WaitGroup
+	for i, slave := range r.slaves {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			status := probeReplica(ctx, slave, masterOffset, masterErr == nil, maxLag)
+			r.lock.Lock()
+			status.Name = r.replicas[i].Name
+			r.replicas[i] = status
+			r.lock.Unlock()
+		}()
+	}
+	wg.Wait()
+}
+
+// probeReplica checks that a slave responds and that its replication offset
+// is within maxLag of the master. When INFO is not available, for example
+// because it is denied by an ACL, only liveness is checked.
+func probeReplica(ctx context.Context, slave redis.UniversalClient, masterOffset int64, hasMasterOffset bool, maxLag int64) ReplicaStatus {
+	status := ReplicaStatus{State: ReplicaHealthy, Lag: -1, LastCheck: time.Now()}
+	if err := slave.Ping(ctx).Err(); err != nil {
+		status.State = ReplicaUnhealthy
+		status.Err = err
+		return status
+	}
+
+	info, err := slave.Info(ctx, "replication").Result()
+	if err != nil {
+		return status
+	}
+	status.State, status.Lag, status.Err = replicaStateFromInfo(parseInfo(info), masterOffset, hasMasterOffset, maxLag)
+	return status
+}
+
+// replicaStateFromInfo evaluates the INFO replication fields of a slave
+func replicaStateFromInfo(fields map[string]string, masterOffset int64, hasMasterOffset bool, maxLag int64) (ReplicaState, int64, error) {
+	if link, ok := fields["master_link_status"]; ok && link != "up" {
+		return ReplicaUnhealthy, -1, errReplicaLinkDown
+	}
+	offset, err := strconv.ParseInt(fields["slave_repl_offset"], 10, 64)
+	if err != nil || !hasMasterOffset {
+		return ReplicaHealthy, -1, nil
+	}
+
+	lag := max(masterOffset-offset, 0)
+	if maxLag > 0 && lag > maxLag {
+		return ReplicaLagging, lag, fmt.Errorf("replica is %d bytes behind master", lag)
+	}
+	return ReplicaHealthy, lag, nil
+}
+
+// replicationOffset reads an offset field of INFO replication
+func replicationOffset(ctx context.Context, client redis.UniversalClient, field string) (int64, error) {
+	info, err := client.Info(ctx, "replication").Result()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(parseInfo(info)[field], 10, 64)
+}
+
+// parseInfo parses the key:value lines of an INFO reply
+func parseInfo(info string) map[string]string {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			fields[key] = value
+		}
+	}
+	return fields
+}
+
+// ReplicaStatuses returns the result of the last probe of every slave
+func (r *RedisClient) ReplicaStatuses() []ReplicaStatus {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	statuses := make([]ReplicaStatus, len(r.replicas))
+	copy(statuses, r.replicas)
+	return statuses
+}
+
+// 添加从节点状态指标
+func reportReplicaMetrics(r *RedisClient, meter metric.Meter) {
+	state, _ := meter.Int64ObservableGauge(
+		"redis.replica.state",
+		metric.WithDescription("Routing state of the replica: 0 healthy, 1 lagging, 2 unhealthy"),
+	)
+	lag, _ := meter.Int64ObservableGauge(
+		"redis.replica.lag",
+		metric.WithDescription("Replication offset difference between master and replica"),
+		metric.WithUnit("By"),
+	)
+
+	// 注册回调
+	meter.RegisterCallback(
+		func(ctx context.Context, o metric.Observer) error {
+			for _, status := range r.ReplicaStatuses() {
+				opts := metric.WithAttributes(attribute.String("redis.role", status.Name))
+				o.ObserveInt64(state, int64(status.State), opts)
+				if status.Lag >= 0 {
+					o.ObserveInt64(lag, status.Lag, opts)
+				}
+			}
+			return nil
+		},
+		state,
+		lag,
+	)
+}
@@ -0,0 +1,81 @@
+package redis
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestGetSlaveDbSkipsUnhealthyReplicas(t *testing.T) {
+	connection := func(mr *miniredis.Miniredis) RedisConnection {
+		port, _ := strconv.Atoi(mr.Port())
+		return RedisConnection{Host: mr.Host(), Port: port}
+	}
+	master := miniredis.RunT(t)
+	slaveA := miniredis.RunT(t)
+	slaveB := miniredis.RunT(t)
+
+	client, err := NewRedisClient(&RedisConfig{
+		Master:       connection(master),
+		Slaves:       []RedisConnection{connection(slaveA), connection(slaveB)},
+		ReplicaProbe: RedisReplicaProbeConfig{Interval: "10ms"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	waitForState := func(i int, state ReplicaState) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for client.ReplicaStatuses()[i].State != state {
+			if time.Now().After(deadline) {
+				t.Fatalf("slave_%d is %v, want %v", i, client.ReplicaStatuses()[i].State, state)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	slaveB.Close()
+	waitForState(1, ReplicaUnhealthy)
+	for i := 0; i < 4; i++ {
+		if db := client.GetSlaveDb(); db != client.slaves[0] {
+			t.Fatalf("GetSlaveDb returned %v, want slave_0", db)
+		}
+	}
+
+	slaveA.Close()
+	waitForState(0, ReplicaUnhealthy)
+	if db := client.GetSlaveDb(); db != client.GetMasterDb() {
+		t.Fatalf("GetSlaveDb returned %v, want the master", db)
+	}
+
+	if err := slaveB.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	waitForState(1, ReplicaHealthy)
+	if db := client.GetSlaveDb(); db != client.slaves[1] {
+		t.Fatalf("GetSlaveDb returned %v, want the recovered slave_1", db)
+	}
+}
+
+func TestReplicaStateFromInfo(t *testing.T) {
+	info := "# Replication\r\nrole:slave\r\nmaster_link_status:up\r\nslave_repl_offset:1000\r\n"
+	fields := parseInfo(info)
+
+	if state, lag, _ := replicaStateFromInfo(fields, 1500, true, 1024); state != ReplicaHealthy || lag != 500 {
+		t.Errorf("got %v with lag %d, want healthy with lag 500", state, lag)
+	}
+	if state, _, err := replicaStateFromInfo(fields, 5000, true, 1024); state != ReplicaLagging || err == nil {
+		t.Errorf("got %v, want lagging", state)
+	}
+	if state, _, _ := replicaStateFromInfo(fields, 5000, true, -1); state != ReplicaHealthy {
+		t.Errorf("got %v, want healthy when the lag check is disabled", state)
+	}
+	fields["master_link_status"] = "down"
+	if state, _, _ := replicaStateFromInfo(fields, 1000, true, 1024); state != ReplicaUnhealthy {
+		t.Errorf("got %v, want unhealthy when the master link is down", state)
+	}
+}
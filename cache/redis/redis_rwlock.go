@@ -0,0 +1,273 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/loongkirin/gdk/lock"
+	"github.com/redis/go-redis/v9"
+)
+
+// RWLocker defines the interface for a distributed shared/exclusive lock.
+// Many readers may hold the lock at the same time, a writer holds it alone.
+type RWLocker interface {
+	// RLock attempts to acquire the lock for reading
+	RLock(ctx context.Context) error
+	// RUnlock releases a read lock
+	RUnlock(ctx context.Context) error
+	// Lock attempts to acquire the lock for writing
+	Lock(ctx context.Context) error
+	// Unlock releases a write lock
+	Unlock(ctx context.Context) error
+	// Refresh extends the lease of the lock held by this instance
+	Refresh(ctx context.Context) error
+}
+
+// Lua scripts for leases
+const (
+	// 获取 Redis 服务器当前时间（毫秒），避免依赖客户端时钟
+	nowScript = `
+		local function now()
+			local t = redis.call("time")
+			return tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+		end`
+
+	// 延长 key 的过期时间，只增不减
+	extendScript = `
+		local function extend(key, ttl)
+			local current = redis.call("pttl", key)
+			if current < ttl then
+				redis.call("pexpire", key, ttl)
+			end
+		end`
+
+	// 读锁脚本：没有写锁时加入读者集合
+	// KEYS[1] 写锁, KEYS[2] 读者集合; ARGV[1] 持有者, ARGV[2] 过期毫秒数
+	rLockScript = nowScript + extendScript + `
+		if redis.call("exists", KEYS[1]) == 1 then
+			return 0
+		end
+		local ttl = tonumber(ARGV[2])
+		local t = now()
+		redis.call("zremrangebyscore", KEYS[2], "-inf", t)
+		redis.call("zadd", KEYS[2], t + ttl, ARGV[1])
+		extend(KEYS[2], ttl)
+		return 1`
+
+	// 写锁脚本：没有写锁且没有有效读者时加锁
+	// KEYS[1] 写锁, KEYS[2] 读者集合; ARGV[1] 持有者, ARGV[2] 过期毫秒数
+	wLockScript = nowScript + `
+		if redis.call("exists", KEYS[1]) == 1 then
+			return 0
+		end
+		redis.call("zremrangebyscore", KEYS[2], "-inf", now())
+		if redis.call("zcard", KEYS[2]) > 0 then
+			return 0
+		end
+		redis.call("set", KEYS[1], ARGV[1], "PX", ARGV[2])
+		return 1`
+
+	// 租约刷新脚本：持有者仍在有序集合中时更新到期时间
+	// KEYS[1] 有序集合; ARGV[1] 持有者, ARGV[2] 过期毫秒数
+	leaseRefreshScript = nowScript + extendScript + `
+		local ttl = tonumber(ARGV[2])
+		local t = now()
+		local expiry = redis.call("zscore", KEYS[1], ARGV[1])
+		if expiry == false or tonumber(expiry) <= t then
+			return 0
+		end
+		redis.call("zadd", KEYS[1], t + ttl, ARGV[1])
+		extend(KEYS[1], ttl)
+		return 1`
+)
+
+// RedisRWLock is a distributed read-write lock. Readers are kept in a sorted
+// set scored by the expiry of their lease, so a reader that dies without
+// unlocking stops blocking writers once its lease expires. Writers do not
+// have priority over new readers.
+type RedisRWLock struct {
+	client     redis.UniversalClient
+	key        string
+	value      string
+	expiration time.Duration
+	state      int32 // atomic, rwLockState
+}
+
+type rwLockState int32
+
+const (
+	rwLockUnlocked rwLockState = iota
+	rwLockReading
+	rwLockWriting
+)
+
+var _ RWLocker = (*RedisRWLock)(nil)
+
+// NewRedisRWLock creates a new Redis-based read-write lock. value identifies
+// this holder and must be unique among the holders of the lock.
+func NewRedisRWLock(client redis.UniversalClient, key, value string, expiration time.Duration) (*RedisRWLock, error) {
+	if client == nil {
+		return nil, errors.New("redis client is required")
+	}
+	if key == "" {
+		return nil, ErrLockKeyRequired
+	}
+	if value == "" {
+		return nil, ErrLockValueRequired
+	}
+	if expiration <= 0 {
+		return nil, errors.New("expiration must be positive")
+	}
+
+	return &RedisRWLock{
+		client:     client,
+		key:        key,
+		value:      value,
+		expiration: expiration,
+	}, nil
+}
+
+// RLock attempts to acquire the lock for reading
+func (l *RedisRWLock) RLock(ctx context.Context) error {
+	return l.acquire(ctx, rwLockReading, rLockScript)
+}
+
+// Lock attempts to acquire the lock for writing
+func (l *RedisRWLock) Lock(ctx context.Context) error {
+	return l.acquire(ctx, rwLockWriting, wLockScript)
+}
+
+// RLockWithRetry attempts to acquire the read lock with exponential backoff
+func (l *RedisRWLock) RLockWithRetry(ctx context.Context, maxRetries int, initialDelay time.Duration) error {
+	return lock.LockWithRetry(ctx, maxRetries, initialDelay, l.RLock)
+}
+
+// LockWithRetry attempts to acquire the write lock with exponential backoff
+func (l *RedisRWLock) LockWithRetry(ctx context.Context, maxRetries int, initialDelay time.Duration) error {
+	return lock.LockWithRetry(ctx, maxRetries, initialDelay, l.Lock)
+}
+
+func (l *RedisRWLock) acquire(ctx context.Context, state rwLockState, script string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if !atomic.CompareAndSwapInt32(&l.state, int32(rwLockUnlocked), int32(state)) {
+		return ErrAlreadyLocked
+	}
+
+	result, err := l.client.Eval(ctx, script, []string{l.writerKey(), l.readersKey()}, l.value, l.expiration.Milliseconds()).Int64()
+	if err != nil {
+		atomic.StoreInt32(&l.state, int32(rwLockUnlocked))
+		return fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	if result == 0 {
+		atomic.StoreInt32(&l.state, int32(rwLockUnlocked))
+		return ErrLockNotObtained
+	}
+	return nil
+}
+
+// RUnlock releases a read lock held by this instance
+func (l *RedisRWLock) RUnlock(ctx context.Context) error {
+	if ctx == nil {
+		return ErrInvalidContext
+	}
+
+	if !atomic.CompareAndSwapInt32(&l.state, int32(rwLockReading), int32(rwLockUnlocked)) {
+		return ErrLockNotHeld
+	}
+
+	removed, err := l.client.ZRem(ctx, l.readersKey(), l.value).Result()
+	if err != nil {
+		atomic.StoreInt32(&l.state, int32(rwLockReading))
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	if removed == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// Unlock releases a write lock held by this instance
+func (l *RedisRWLock) Unlock(ctx context.Context) error {
+	if ctx == nil {
+		return ErrInvalidContext
+	}
+
+	if !atomic.CompareAndSwapInt32(&l.state, int32(rwLockWriting), int32(rwLockUnlocked)) {
+		return ErrLockNotHeld
+	}
+
+	result, err := l.client.Eval(ctx, unlockScript, []string{l.writerKey()}, l.value).Int64()
+	if err != nil {
+		atomic.StoreInt32(&l.state, int32(rwLockWriting))
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	if result == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// Refresh extends the lease of the read or write lock held by this instance
+func (l *RedisRWLock) Refresh(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var result int64
+	var err error
+	switch rwLockState(atomic.LoadInt32(&l.state)) {
+	case rwLockReading:
+		result, err = l.client.Eval(ctx, leaseRefreshScript, []string{l.readersKey()}, l.value, l.expiration.Milliseconds()).Int64()
+	case rwLockWriting:
+		result, err = l.client.Eval(ctx, refreshScript, []string{l.writerKey()}, l.value, l.expiration.Milliseconds()).Int64()
+	default:
+		return ErrLockNotHeld
+	}
+	if err != nil {
+		return err
+	}
+	if result == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// AutoRefresh starts a goroutine to automatically refresh the lock
+// It returns a channel that will receive refresh errors and a stop function
+func (l *RedisRWLock) AutoRefresh(ctx context.Context, refreshInterval time.Duration) (chan error, func()) {
+	if refreshInterval <= 0 {
+		refreshInterval = l.expiration / 3
+	}
+	return lock.AutoRefresh(ctx, refreshInterval, l.Refresh)
+}
+
+// String implements fmt.Stringer
+func (l *RedisRWLock) String() string {
+	return fmt.Sprintf("RedisRWLock{key: %s, expiration: %v}", l.key, l.expiration)
+}
+
+func (l *RedisRWLock) writerKey() string {
+	return slotKey(l.key, ":writer")
+}
+
+func (l *RedisRWLock) readersKey() string {
+	return slotKey(l.key, ":readers")
+}
+
+// slotKey derives a key from key that hashes to the same Redis Cluster slot,
+// so scripts touching both keys also work under cluster mode
+func slotKey(key, suffix string) string {
+	if start := strings.Index(key, "{"); start >= 0 {
+		if end := strings.Index(key[start+1:], "}"); end > 0 {
+			return key + suffix
+		}
+	}
+	return "{" + key + "}" + suffix
+}
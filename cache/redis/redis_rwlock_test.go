@@ -0,0 +1,104 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRedisRWLock(t *testing.T) {
+	ctx := context.Background()
+	_, client := newTestRedisClient(t)
+
+	readerA, _ := NewRedisRWLock(client, "config", "reader-a", time.Minute)
+	readerB, _ := NewRedisRWLock(client, "config", "reader-b", time.Minute)
+	writer, _ := NewRedisRWLock(client, "config", "writer", time.Minute)
+
+	if err := readerA.RLock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := readerB.RLock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Lock(ctx); !errors.Is(err, ErrLockNotObtained) {
+		t.Fatalf("writer got %v while readers hold the lock", err)
+	}
+	if err := readerA.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	readerA.RUnlock(ctx)
+	readerB.RUnlock(ctx)
+
+	if err := writer.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := readerA.RLock(ctx); !errors.Is(err, ErrLockNotObtained) {
+		t.Fatalf("reader got %v while the writer holds the lock", err)
+	}
+	if err := writer.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := readerA.RLock(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRedisRWLockReaderLeaseExpires(t *testing.T) {
+	ctx := context.Background()
+	mr, client := newTestRedisClient(t)
+
+	reader, _ := NewRedisRWLock(client, "config", "reader", time.Second)
+	writer, _ := NewRedisRWLock(client, "config", "writer", time.Second)
+	if err := reader.RLock(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 读者租约以服务器时间计算，推进 miniredis 时钟模拟读者崩溃
+	mr.SetTime(time.Now().Add(2 * time.Second))
+	if err := writer.Lock(ctx); err != nil {
+		t.Fatalf("writer blocked by an expired reader lease: %v", err)
+	}
+}
+
+func TestRedisSemaphore(t *testing.T) {
+	ctx := context.Background()
+	mr, client := newTestRedisClient(t)
+
+	var holders []*RedisSemaphore
+	for _, name := range []string{"a", "b", "c"} {
+		s, err := NewRedisSemaphore(client, "exports:tenant-1", name, 2, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		holders = append(holders, s)
+	}
+
+	if err := holders[0].Acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := holders[1].Acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := holders[2].Acquire(ctx); !errors.Is(err, ErrLockNotObtained) {
+		t.Fatalf("third holder got %v, want ErrLockNotObtained", err)
+	}
+	if available, err := holders[0].Available(ctx); err != nil || available != 0 {
+		t.Errorf("Available = %d, %v, want 0", available, err)
+	}
+
+	if err := holders[0].Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := holders[2].Acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	mr.SetTime(time.Now().Add(2 * time.Second))
+	if available, err := holders[0].Available(ctx); err != nil || available != 2 {
+		t.Errorf("Available = %d, %v after leases expired, want 2", available, err)
+	}
+	if err := holders[1].Refresh(ctx); !errors.Is(err, ErrLockNotHeld) {
+		t.Errorf("refresh of an expired lease got %v, want ErrLockNotHeld", err)
+	}
+}
@@ -0,0 +1,154 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// testConnections parses a comma separated list of host:port addresses
+func testConnections(t *testing.T, value string) []RedisConnection {
+	t.Helper()
+	var conns []RedisConnection
+	for _, addr := range strings.Split(value, ",") {
+		host, port, err := net.SplitHostPort(strings.TrimSpace(addr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns = append(conns, RedisConnection{Host: host, Port: p})
+	}
+	return conns
+}
+
+// testRedisClient exercises RedisStore and RedisLock against a client
+func testRedisClient(t *testing.T, cfg *RedisConfig) {
+	ctx := context.Background()
+	client, err := NewRedisClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if err := client.HealthCheck(); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewRedisStore(client.GetMasterDb(), "gdk-test:"+cfg.Mode+":", 0)
+	defer store.Flush(ctx)
+	items := map[string]interface{}{}
+	keys := []string{}
+	for i := 0; i < 20; i++ {
+		key := "key" + strconv.Itoa(i)
+		items[key] = strconv.Itoa(i)
+		keys = append(keys, key)
+	}
+	if err := store.SetMulti(ctx, items, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	values, misses, err := store.GetMulti(ctx, append(keys, "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != len(keys) || len(misses) != 1 {
+		t.Errorf("got %d values and misses %v", len(values), misses)
+	}
+	if err := store.DeleteMulti(ctx, keys[:10]); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeletePrefix(ctx, "key1"); err != nil {
+		t.Fatal(err)
+	}
+	values, _, err = store.GetMulti(ctx, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 0 {
+		t.Errorf("expected every key to be deleted, got %v", values)
+	}
+
+	lock, err := NewRedisLock(client.GetMasterDb(), "gdk-test:"+cfg.Mode+":lock", "owner", time.Second*5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := NewRedisLock(client.GetMasterDb(), "gdk-test:"+cfg.Mode+":lock", "other", time.Second*5)
+	if err := lock.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Lock(ctx); !errors.Is(err, ErrLockNotObtained) {
+		t.Errorf("second owner got %v, want ErrLockNotObtained", err)
+	}
+	if err := lock.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRedisClientStandalone(t *testing.T) {
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	testRedisClient(t, &RedisConfig{
+		Master: RedisConnection{Host: mr.Host(), Port: port},
+		Slaves: []RedisConnection{{Host: mr.Host(), Port: port}},
+	})
+}
+
+// miniredis answers CLUSTER SLOTS as a single node owning every slot, which
+// is enough to run the cluster code paths of RedisStore
+func TestRedisClientClusterSingleNode(t *testing.T) {
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	testRedisClient(t, &RedisConfig{
+		Mode:    RedisModeCluster,
+		Cluster: RedisClusterConfig{Nodes: []RedisConnection{{Host: mr.Host(), Port: port}}},
+	})
+}
+
+func TestRedisClientUnsupportedMode(t *testing.T) {
+	if _, err := NewRedisClient(&RedisConfig{Mode: "ring"}); err == nil {
+		t.Error("expected an error for an unsupported mode")
+	}
+}
+
+// The topology tests run against the processes started by
+// testdata/redis-topology.sh and are skipped when the addresses are not set
+func TestRedisClientSentinelTopology(t *testing.T) {
+	addrs := os.Getenv("GDK_REDIS_SENTINEL_ADDRS")
+	if addrs == "" {
+		t.Skip("GDK_REDIS_SENTINEL_ADDRS is not set")
+	}
+	masterName := os.Getenv("GDK_REDIS_SENTINEL_MASTER")
+	if masterName == "" {
+		masterName = "mymaster"
+	}
+	testRedisClient(t, &RedisConfig{
+		Mode: RedisModeSentinel,
+		Sentinel: RedisSentinelConfig{
+			MasterName:  masterName,
+			Sentinels:   testConnections(t, addrs),
+			ReplicaRead: true,
+		},
+	})
+}
+
+func TestRedisClientClusterTopology(t *testing.T) {
+	addrs := os.Getenv("GDK_REDIS_CLUSTER_ADDRS")
+	if addrs == "" {
+		t.Skip("GDK_REDIS_CLUSTER_ADDRS is not set")
+	}
+	testRedisClient(t, &RedisConfig{
+		Mode:    RedisModeCluster,
+		Cluster: RedisClusterConfig{Nodes: testConnections(t, addrs), ReadOnly: true},
+	})
+}
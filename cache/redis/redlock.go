@@ -0,0 +1,266 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/loongkirin/gdk/cache"
+	"github.com/loongkirin/gdk/lock"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedlockOptions represents configuration options for Redlock
+type RedlockOptions struct {
+	// DriftFactor is the clock drift allowance as a fraction of the expiration
+	DriftFactor float64
+	// DriftConstant is added to the drift to account for Redis expiry precision
+	DriftConstant time.Duration
+	// RequestTimeout bounds each request to a single instance, so an instance
+	// that is down does not eat the validity time of the lock
+	RequestTimeout time.Duration
+}
+
+// DefaultRedlockOptions returns the default options for Redlock
+func DefaultRedlockOptions() RedlockOptions {
+	return RedlockOptions{
+		DriftFactor:    0.01,
+		DriftConstant:  2 * time.Millisecond,
+		RequestTimeout: 50 * time.Millisecond,
+	}
+}
+
+// Redlock is a distributed lock implementing the Redlock algorithm over N
+// independent Redis masters. The lock is held when a majority of the
+// instances accepted it within its validity time, so it survives the loss of
+// a minority of the instances.
+type Redlock struct {
+	clients    []redis.UniversalClient
+	key        string
+	value      string
+	expiration time.Duration
+	quorum     int
+	options    RedlockOptions
+	state      int32 // atomic
+	validUntil int64 // atomic, unix nanoseconds
+}
+
+var (
+	_ lock.Locker = (*Redlock)(nil)
+	_ cache.Lock  = (*Redlock)(nil)
+)
+
+// NewRedlock creates a new Redlock over the given independent instances
+func NewRedlock(clients []redis.UniversalClient, key, value string, expiration time.Duration, opts RedlockOptions) (*Redlock, error) {
+	if len(clients) == 0 {
+		return nil, errors.New("at least one redis client is required")
+	}
+	for _, client := range clients {
+		if client == nil {
+			return nil, errors.New("redis client is required")
+		}
+	}
+	if key == "" {
+		return nil, ErrLockKeyRequired
+	}
+	if value == "" {
+		return nil, ErrLockValueRequired
+	}
+	if expiration <= 0 {
+		return nil, errors.New("expiration must be positive")
+	}
+
+	defaults := DefaultRedlockOptions()
+	if opts.DriftFactor <= 0 {
+		opts.DriftFactor = defaults.DriftFactor
+	}
+	if opts.DriftConstant <= 0 {
+		opts.DriftConstant = defaults.DriftConstant
+	}
+	if opts.RequestTimeout <= 0 {
+		opts.RequestTimeout = defaults.RequestTimeout
+	}
+
+	return &Redlock{
+		clients:    clients,
+		key:        key,
+		value:      value,
+		expiration: expiration,
+		quorum:     len(clients)/2 + 1,
+		options:    opts,
+	}, nil
+}
+
+// Lock attempts to acquire the lock on a majority of the instances
+func (l *Redlock) Lock(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if !atomic.CompareAndSwapInt32(&l.state, int32(LockStateUnlocked), int32(LockStateLocked)) {
+		return ErrAlreadyLocked
+	}
+
+	start := time.Now()
+	acquired, err := l.eachInstance(ctx, func(ctx context.Context, client redis.UniversalClient) (bool, error) {
+		return client.SetNX(ctx, l.key, l.value, l.expiration).Result()
+	})
+	if l.valid(start, acquired) {
+		return nil
+	}
+
+	// 未达到多数派或有效时间已耗尽，释放所有实例上可能已获得的锁
+	l.release(context.WithoutCancel(ctx))
+	atomic.StoreInt32(&l.state, int32(LockStateUnlocked))
+	if acquired < l.quorum && err != nil && acquired+l.failures(err) >= l.quorum {
+		return fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	return ErrLockNotObtained
+}
+
+// Unlock releases the lock on every instance
+func (l *Redlock) Unlock(ctx context.Context) error {
+	if ctx == nil {
+		return ErrInvalidContext
+	}
+
+	if !atomic.CompareAndSwapInt32(&l.state, int32(LockStateLocked), int32(LockStateUnlocked)) {
+		return ErrLockNotHeld
+	}
+
+	released, err := l.release(ctx)
+	if released == 0 {
+		if err != nil {
+			return fmt.Errorf("failed to release lock: %w", err)
+		}
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// Refresh extends the lock on every instance, the lock is only still held
+// when a majority of the instances extended it within the validity time
+func (l *Redlock) Refresh(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	start := time.Now()
+	extended, err := l.eachInstance(ctx, func(ctx context.Context, client redis.UniversalClient) (bool, error) {
+		result, err := client.Eval(ctx, refreshScript, []string{l.key}, l.value, l.expiration.Milliseconds()).Int64()
+		return result == 1, err
+	})
+	if l.valid(start, extended) {
+		return nil
+	}
+	if err != nil && extended < l.quorum {
+		return fmt.Errorf("failed to refresh lock: %w", err)
+	}
+	return ErrLockNotHeld
+}
+
+// LockWithRetry attempts to acquire the lock with exponential backoff
+func (l *Redlock) LockWithRetry(ctx context.Context, maxRetries int, initialDelay time.Duration) error {
+	return lock.LockWithRetry(ctx, maxRetries, initialDelay, l.Lock)
+}
+
+// AutoRefresh starts a goroutine to automatically refresh the lock
+// It returns a channel that will receive refresh errors and a stop function
+func (l *Redlock) AutoRefresh(ctx context.Context, refreshInterval time.Duration) (chan error, func()) {
+	if refreshInterval <= 0 {
+		refreshInterval = l.expiration / 3
+	}
+	return lock.AutoRefresh(ctx, refreshInterval, l.Refresh)
+}
+
+// Close releases the lock if it is still held
+func (l *Redlock) Close(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if LockState(atomic.LoadInt32(&l.state)) != LockStateLocked {
+		return nil
+	}
+	if err := l.Unlock(ctx); err != nil && err != ErrLockNotHeld {
+		return err
+	}
+	return nil
+}
+
+// Validity returns how long the lock is still guaranteed to be held, zero
+// when it is not held or has expired
+func (l *Redlock) Validity() time.Duration {
+	if LockState(atomic.LoadInt32(&l.state)) != LockStateLocked {
+		return 0
+	}
+	return max(time.Until(time.Unix(0, atomic.LoadInt64(&l.validUntil))), 0)
+}
+
+// String implements fmt.Stringer
+func (l *Redlock) String() string {
+	return fmt.Sprintf("Redlock{key: %s, instances: %d, quorum: %d, expiration: %v}", l.key, len(l.clients), l.quorum, l.expiration)
+}
+
+// valid records the validity time of an operation started at start that
+// succeeded on n instances and reports whether the lock is held
+func (l *Redlock) valid(start time.Time, n int) bool {
+	drift := time.Duration(float64(l.expiration)*l.options.DriftFactor) + l.options.DriftConstant
+	validity := l.expiration - time.Since(start) - drift
+	if n < l.quorum || validity <= 0 {
+		return false
+	}
+	atomic.StoreInt64(&l.validUntil, start.Add(l.expiration-drift).UnixNano())
+	return true
+}
+
+// release removes the lock from every instance that still holds our value
+func (l *Redlock) release(ctx context.Context) (int, error) {
+	return l.eachInstance(ctx, func(ctx context.Context, client redis.UniversalClient) (bool, error) {
+		result, err := client.Eval(ctx, unlockScript, []string{l.key}, l.value).Int64()
+		return result == 1, err
+	})
+}
+
+// failures returns the number of instances that failed with an error
+func (l *Redlock) failures(err error) int {
+	var multi interface{ Unwrap() []error }
+	if errors.As(err, &multi) {
+		return len(multi.Unwrap())
+	}
+	return 1
+}
+
+// eachInstance runs f against every instance concurrently, each with its own
+// timeout, and returns the number of instances where f succeeded
+func (l *Redlock) eachInstance(ctx context.Context, f func(ctx context.Context, client redis.UniversalClient) (bool, error)) (int, error) {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		n    int
+		errs []error
+	)
+	for _, client := range l.clients {
+		wg.Add(1)
+		go func(client redis.UniversalClient) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, l.options.RequestTimeout)
+			defer cancel()
+
+			ok, err := f(ctx, client)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil && err != redis.Nil {
+				errs = append(errs, err)
+				return
+			}
+			if ok {
+				n++
+			}
+		}(client)
+	}
+	wg.Wait()
+	return n, errors.Join(errs...)
+}
@@ -0,0 +1,93 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedlockClients(t *testing.T, n int) ([]*miniredis.Miniredis, []redis.UniversalClient) {
+	t.Helper()
+	var servers []*miniredis.Miniredis
+	var clients []redis.UniversalClient
+	for i := 0; i < n; i++ {
+		mr, client := newTestRedisClient(t)
+		servers = append(servers, mr)
+		clients = append(clients, client)
+	}
+	return servers, clients
+}
+
+func TestRedlockQuorum(t *testing.T) {
+	ctx := context.Background()
+	servers, clients := newTestRedlockClients(t, 3)
+
+	lock, err := NewRedlock(clients, "redlock", "owner", 10*time.Second, DefaultRedlockOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := NewRedlock(clients, "redlock", "other", 10*time.Second, DefaultRedlockOptions())
+
+	if err := lock.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if lock.Validity() <= 0 {
+		t.Error("expected a positive validity")
+	}
+	if err := other.Lock(ctx); !errors.Is(err, ErrLockNotObtained) {
+		t.Errorf("second owner got %v, want ErrLockNotObtained", err)
+	}
+	// 失败的获取不能留下部分锁
+	if err := lock.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for i, mr := range servers {
+		if mr.Exists("redlock") {
+			t.Errorf("instance %d still holds the lock", i)
+		}
+	}
+
+	// 少数实例宕机时仍能获得锁
+	servers[0].Close()
+	if err := lock.Lock(ctx); err != nil {
+		t.Fatalf("lock with one instance down: %v", err)
+	}
+	if err := lock.Refresh(ctx); err != nil {
+		t.Fatalf("refresh with one instance down: %v", err)
+	}
+	if err := lock.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 多数实例宕机时无法获得锁
+	servers[1].Close()
+	if err := lock.Lock(ctx); err == nil {
+		t.Fatal("expected lock to fail without a quorum")
+	}
+	if servers[2].Exists("redlock") {
+		t.Error("failed lock left a partial lock behind")
+	}
+}
+
+func TestRedlockLockWithRetry(t *testing.T) {
+	ctx := context.Background()
+	_, clients := newTestRedlockClients(t, 3)
+
+	holder, _ := NewRedlock(clients, "redlock", "holder", 200*time.Millisecond, DefaultRedlockOptions())
+	waiter, _ := NewRedlock(clients, "redlock", "waiter", time.Second, DefaultRedlockOptions())
+	if err := holder.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		holder.Unlock(ctx)
+	}()
+	if err := waiter.LockWithRetry(ctx, 10, 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	waiter.Close(ctx)
+}
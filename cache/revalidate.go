@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// staleMarker prefixes the entries written by GetOrRevalidate. It is followed
+// by the soft expiry in unix milliseconds, a separator and the encoded value.
+const staleMarker = "\x00gdk:cache:swr:"
+
+var errInvalidTTL = errors.New("cache: soft ttl must be positive and shorter than hard ttl")
+
+// encodeEntry wraps data with the soft expiry of the entry
+func encodeEntry(data []byte, softTTL time.Duration) []byte {
+	softExpiry := strconv.FormatInt(time.Now().Add(softTTL).UnixMilli(), 10)
+	entry := make([]byte, 0, len(staleMarker)+len(softExpiry)+1+len(data))
+	entry = append(entry, staleMarker...)
+	entry = append(entry, softExpiry...)
+	entry = append(entry, 0)
+	return append(entry, data...)
+}
+
+// decodeEntry unwraps an entry written by encodeEntry, stale reports whether
+// its soft TTL has passed. Other entries are returned as they are.
+func decodeEntry(raw string) (data string, stale bool) {
+	rest, found := strings.CutPrefix(raw, staleMarker)
+	if !found {
+		return raw, false
+	}
+	softExpiry, data, found := strings.Cut(rest, "\x00")
+	if !found {
+		return raw, false
+	}
+	expiry, err := strconv.ParseInt(softExpiry, 10, 64)
+	if err != nil {
+		return raw, false
+	}
+	return data, time.Now().UnixMilli() >= expiry
+}
+
+// GetOrRevalidate returns the cached value of key with stale-while-revalidate
+// semantics. Until softTTL the cached value is returned as is. Between softTTL
+// and hardTTL the stale value is returned immediately and a single background
+// refresh calls loader, once per process and once across replicas when a
+// LockFactory is set. After hardTTL the entry is gone and the call loads like
+// GetOrLoad. A failed refresh keeps serving the stale value until hardTTL.
+func (l *Loader[T]) GetOrRevalidate(ctx context.Context, key string, softTTL, hardTTL time.Duration, loader LoadFunc[T]) (T, error) {
+	if softTTL <= 0 || hardTTL <= softTTL {
+		var zero T
+		return zero, errInvalidTTL
+	}
+
+	if value, hit, stale, err := l.lookup(ctx, key); hit {
+		if stale {
+			l.revalidate(ctx, key, softTTL, hardTTL, loader)
+		}
+		return value, err
+	}
+	return l.loadOnce(ctx, key, softTTL, hardTTL, loader)
+}
+
+// revalidate refreshes key in the background unless a refresh is running
+func (l *Loader[T]) revalidate(ctx context.Context, key string, softTTL, hardTTL time.Duration, loader LoadFunc[T]) {
+	if _, running := l.refreshing.LoadOrStore(key, struct{}{}); running {
+		return
+	}
+
+	// 后台刷新不受调用方 ctx 取消的影响
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), l.options.RefreshTimeout)
+	go func() {
+		defer l.refreshing.Delete(key)
+		defer cancel()
+
+		if l.options.LockFactory != nil {
+			lock, err := l.options.LockFactory("refresh:" + key)
+			if err != nil {
+				return
+			}
+			// Another replica is refreshing the entry
+			if err := lock.Lock(ctx); err != nil {
+				return
+			}
+			defer lock.Unlock(context.WithoutCancel(ctx))
+			if _, hit, stale, _ := l.lookup(ctx, key); hit && !stale {
+				return
+			}
+		}
+		l.load(ctx, key, softTTL, hardTTL, loader)
+	}()
+}
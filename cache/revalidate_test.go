@@ -0,0 +1,76 @@
+package cache_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/loongkirin/gdk/cache"
+	"github.com/loongkirin/gdk/cache/memeorycache"
+	cacheredis "github.com/loongkirin/gdk/cache/redis"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestLoaderRevalidate(t *testing.T) {
+	stores := map[string]func(t *testing.T) (cache.ContextCacheStore, func(time.Duration)){
+		"memory": func(t *testing.T) (cache.ContextCacheStore, func(time.Duration)) {
+			return memeorycache.NewInMemoryStore(time.Minute), time.Sleep
+		},
+		"redis": func(t *testing.T) (cache.ContextCacheStore, func(time.Duration)) {
+			mr := miniredis.RunT(t)
+			client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			t.Cleanup(func() { client.Close() })
+			// miniredis 的过期需要手动推进
+			return cacheredis.NewRedisStore(client, "swr:", 0), func(d time.Duration) {
+				time.Sleep(d)
+				mr.FastForward(d)
+			}
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			store, wait := newStore(t)
+			loader := cache.NewLoader[int64](store, nil, cache.DefaultLoaderOptions())
+
+			var calls int64
+			load := func(ctx context.Context) (int64, error) {
+				time.Sleep(20 * time.Millisecond)
+				return atomic.AddInt64(&calls, 1), nil
+			}
+			softTTL, hardTTL := 50*time.Millisecond, 300*time.Millisecond
+
+			if v, err := loader.GetOrRevalidate(ctx, "key", softTTL, hardTTL, load); err != nil || v != 1 {
+				t.Fatalf("first read got %d, %v", v, err)
+			}
+
+			// 软过期后立即返回旧值，只触发一次后台刷新
+			wait(60 * time.Millisecond)
+			for i := 0; i < 10; i++ {
+				start := time.Now()
+				if v, err := loader.GetOrRevalidate(ctx, "key", softTTL, hardTTL, load); err != nil || v != 1 {
+					t.Fatalf("stale read got %d, %v", v, err)
+				}
+				if elapsed := time.Since(start); elapsed > 15*time.Millisecond {
+					t.Errorf("stale read blocked for %v", elapsed)
+				}
+			}
+			time.Sleep(50 * time.Millisecond)
+			if got := atomic.LoadInt64(&calls); got != 2 {
+				t.Fatalf("loader called %d times, want 2", got)
+			}
+			if v, err := loader.GetOrRevalidate(ctx, "key", softTTL, hardTTL, load); err != nil || v != 2 {
+				t.Fatalf("refreshed read got %d, %v", v, err)
+			}
+
+			// 硬过期后视为未命中，同步加载
+			wait(hardTTL + 10*time.Millisecond)
+			if v, err := loader.GetOrRevalidate(ctx, "key", softTTL, hardTTL, load); err != nil || v != 3 {
+				t.Fatalf("read after hard ttl got %d, %v", v, err)
+			}
+		})
+	}
+}
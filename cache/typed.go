@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Typed is a typed facade on top of a ContextCacheStore. Values are encoded
+// with the configured Codec and stored as raw bytes, so every backend
+// round-trips the same value the same way. A legacy CacheStore can be wrapped
+// with NewContextCacheStoreAdapter.
+type Typed[T any] struct {
+	store ContextCacheStore
+	codec Codec
+}
+
+// NewTyped creates a Typed cache using codec, JSON is used when codec is nil
+func NewTyped[T any](store ContextCacheStore, codec Codec) *Typed[T] {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+	return &Typed[T]{
+		store: store,
+		codec: codec,
+	}
+}
+
+// NewTypedWithCodecName creates a Typed cache using a registered codec
+func NewTypedWithCodecName[T any](store ContextCacheStore, codecName string) (*Typed[T], error) {
+	codec, err := GetCodec(codecName)
+	if err != nil {
+		return nil, err
+	}
+	return NewTyped[T](store, codec), nil
+}
+
+// Store returns the underlying ContextCacheStore
+func (t *Typed[T]) Store() ContextCacheStore {
+	return t.store
+}
+
+// Codec returns the codec used to encode values
+func (t *Typed[T]) Codec() Codec {
+	return t.codec
+}
+
+// Get retrieves and decodes an item from the cache
+func (t *Typed[T]) Get(ctx context.Context, key string) (T, error) {
+	var value T
+	raw, err := t.store.Get(ctx, key)
+	if err != nil {
+		return value, err
+	}
+	if err := t.codec.Unmarshal([]byte(raw), &value); err != nil {
+		return value, err
+	}
+	return value, nil
+}
+
+// Set encodes value and sets it to the cache, replacing any existing item
+func (t *Typed[T]) Set(ctx context.Context, key string, value T, expire time.Duration) error {
+	data, err := t.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return t.store.Set(ctx, key, data, expire)
+}
+
+// Add encodes value and adds it to the cache only if the key doesn't exist
+func (t *Typed[T]) Add(ctx context.Context, key string, value T, expire time.Duration) error {
+	data, err := t.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return t.store.Add(ctx, key, data, expire)
+}
+
+// Replace encodes value and sets it to the cache only if the key already exists
+func (t *Typed[T]) Replace(ctx context.Context, key string, value T, expire time.Duration) error {
+	data, err := t.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return t.store.Replace(ctx, key, data, expire)
+}
+
+// Delete removes an item from the cache
+func (t *Typed[T]) Delete(ctx context.Context, key string) error {
+	return t.store.Delete(ctx, key)
+}
@@ -0,0 +1,59 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/loongkirin/gdk/cache"
+	"github.com/loongkirin/gdk/cache/memeorycache"
+)
+
+type typedUser struct {
+	Id   string
+	Name string
+	Tags []string
+}
+
+func TestTypedRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	for _, name := range []string{cache.CodecJSON, cache.CodecGob, cache.CodecMsgpack} {
+		store := memeorycache.NewInMemoryStore(time.Minute)
+		typed, err := cache.NewTypedWithCodecName[typedUser](store, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := typedUser{Id: "1", Name: "loong", Tags: []string{"a", "b"}}
+		if err := typed.Set(ctx, "user", want, cache.DEFAULT); err != nil {
+			t.Fatalf("%s: set failed: %v", name, err)
+		}
+		got, err := typed.Get(ctx, "user")
+		if err != nil {
+			t.Fatalf("%s: get failed: %v", name, err)
+		}
+		if got.Id != want.Id || got.Name != want.Name || len(got.Tags) != 2 {
+			t.Errorf("%s: got %+v, want %+v", name, got, want)
+		}
+
+		if err := typed.Add(ctx, "user", want, cache.DEFAULT); err == nil {
+			t.Errorf("%s: add of existing key should fail", name)
+		}
+		if err := typed.Replace(ctx, "missing", want, cache.DEFAULT); err == nil {
+			t.Errorf("%s: replace of missing key should fail", name)
+		}
+	}
+}
+
+func TestTypedMiss(t *testing.T) {
+	typed := cache.NewTyped[int](memeorycache.NewInMemoryStore(time.Minute), nil)
+	if _, err := typed.Get(context.Background(), "missing"); err == nil {
+		t.Error("get of missing key should fail")
+	}
+}
+
+func TestGetCodecNotRegistered(t *testing.T) {
+	if _, err := cache.GetCodec("xml"); err == nil {
+		t.Error("unregistered codec should return an error")
+	}
+}
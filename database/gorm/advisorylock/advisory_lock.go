@@ -0,0 +1,188 @@
+package advisorylock
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	database "github.com/loongkirin/gdk/database/gorm"
+	"github.com/loongkirin/gdk/lock"
+)
+
+// AdvisoryLock is a Locker backed by a Postgres session level advisory lock
+// on the master of a DbContext. The lock pins one connection of the pool
+// while it is held, so it is released by Postgres when that connection or
+// the process dies, which plays the role of the lease expiry of the Redis
+// lock. Refresh checks that the session still holds the lock.
+type AdvisoryLock struct {
+	dbContext database.DbContext
+	key       string
+	id        int64
+	mu        sync.Mutex
+	conn      *sql.Conn
+}
+
+var _ lock.Locker = (*AdvisoryLock)(nil)
+
+// NewAdvisoryLock creates a new advisory lock, key is hashed to the 64 bit
+// lock id used by pg_advisory_lock
+func NewAdvisoryLock(dbContext database.DbContext, key string) (*AdvisoryLock, error) {
+	if dbContext == nil {
+		return nil, errors.New("db context is required")
+	}
+	if key == "" {
+		return nil, lock.ErrLockKeyRequired
+	}
+
+	return &AdvisoryLock{
+		dbContext: dbContext,
+		key:       key,
+		id:        LockId(key),
+	}, nil
+}
+
+// NewFactory returns a lock.Factory creating AdvisoryLocks on dbContext.
+// Advisory locks have no lease, the expiration is ignored and the lock lasts
+// until it is unlocked or its session ends.
+func NewFactory(dbContext database.DbContext) lock.Factory {
+	return func(key string, expiration time.Duration) (lock.Locker, error) {
+		return NewAdvisoryLock(dbContext, key)
+	}
+}
+
+// LockId returns the advisory lock id of key
+func LockId(key string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return int64(h.Sum64())
+}
+
+// Lock attempts to acquire the lock without waiting
+func (l *AdvisoryLock) Lock(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn != nil {
+		return lock.ErrAlreadyLocked
+	}
+
+	sqlDB, err := l.dbContext.GetMasterDb().DB()
+	if err != nil {
+		return fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire lock: %w", err)
+	}
+
+	var ok bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", l.id).Scan(&ok); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	if !ok {
+		conn.Close()
+		return lock.ErrLockNotObtained
+	}
+
+	l.conn = conn
+	return nil
+}
+
+// LockWithRetry attempts to acquire the lock with exponential backoff
+func (l *AdvisoryLock) LockWithRetry(ctx context.Context, maxRetries int, initialDelay time.Duration) error {
+	return lock.LockWithRetry(ctx, maxRetries, initialDelay, l.Lock)
+}
+
+// Unlock releases the lock and returns its connection to the pool
+func (l *AdvisoryLock) Unlock(ctx context.Context) error {
+	if ctx == nil {
+		return lock.ErrInvalidContext
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return lock.ErrLockNotHeld
+	}
+	conn := l.conn
+	l.conn = nil
+	defer conn.Close()
+
+	var ok bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_advisory_unlock($1)", l.id).Scan(&ok); err != nil {
+		// 丢弃连接而不是放回连接池，会话结束后 Postgres 会释放锁
+		conn.Raw(func(driverConn interface{}) error { return driver.ErrBadConn })
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	if !ok {
+		return lock.ErrLockNotHeld
+	}
+	return nil
+}
+
+// Refresh checks that the session still holds the lock, advisory locks do
+// not expire so there is nothing to extend
+func (l *AdvisoryLock) Refresh(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return lock.ErrLockNotHeld
+	}
+
+	// bigint 类型的 advisory lock 在 pg_locks 中拆分为 classid（高 32 位）和 objid（低 32 位）
+	var held bool
+	err := l.conn.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM pg_locks
+			WHERE locktype = 'advisory' AND granted AND pid = pg_backend_pid()
+				AND classid::bigint = $1 AND objid::bigint = $2 AND objsubid = 1
+		)`, int64(uint32(uint64(l.id)>>32)), int64(uint32(l.id))).Scan(&held)
+	if err != nil {
+		return err
+	}
+	if !held {
+		return lock.ErrLockNotHeld
+	}
+	return nil
+}
+
+// AutoRefresh starts a goroutine that periodically checks the lock is held
+// It returns a channel that will receive refresh errors and a stop function
+func (l *AdvisoryLock) AutoRefresh(ctx context.Context, refreshInterval time.Duration) (chan error, func()) {
+	if refreshInterval <= 0 {
+		refreshInterval = 10 * time.Second
+	}
+	return lock.AutoRefresh(ctx, refreshInterval, l.Refresh)
+}
+
+// Close releases the lock if it is still held
+func (l *AdvisoryLock) Close(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := l.Unlock(ctx); err != nil && err != lock.ErrLockNotHeld {
+		return err
+	}
+	return nil
+}
+
+// String implements fmt.Stringer
+func (l *AdvisoryLock) String() string {
+	return fmt.Sprintf("AdvisoryLock{key: %s, id: %d}", l.key, l.id)
+}
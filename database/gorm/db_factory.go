@@ -1,20 +1,66 @@
 package gorm
 
 import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
 	database "github.com/loongkirin/gdk/database"
 	gdkpostgres "github.com/loongkirin/gdk/database/gorm/postgres"
 )
 
-func CreateDbContext(cfg *database.DbConfig) DbContext {
-	var dbcontext DbContext
-	switch cfg.DbType {
-	case "postgres":
-		pgDbContext, err := gdkpostgres.NewPostgresDbContext(cfg)
+const (
+	DialectPostgres = "postgres"
+	DialectMySQL    = "mysql"
+	DialectSQLite   = "sqlite"
+)
+
+// DialectFactory creates the DbContext of a dialect from its configuration
+type DialectFactory func(cfg *database.DbConfig) (DbContext, error)
+
+var dialects sync.Map
+
+func init() {
+	RegisterDialect(DialectPostgres, func(cfg *database.DbConfig) (DbContext, error) {
+		dbContext, err := gdkpostgres.NewPostgresDbContext(cfg)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
-		dbcontext = pgDbContext
-	}
+		return dbContext, nil
+	})
+}
 
-	return dbcontext
+// RegisterDialect registers the factory of a DbType, replacing any factory
+// previously registered with the same name. Postgres is always registered,
+// the other dialects register themselves when their package is imported, so
+// only the drivers in use are linked:
+//
+//	import _ "github.com/loongkirin/gdk/database/gorm/mysql"
+func RegisterDialect(dbType string, factory DialectFactory) {
+	dialects.Store(dbType, factory)
+}
+
+// Dialects returns the registered DbTypes in alphabetical order
+func Dialects() []string {
+	var names []string
+	dialects.Range(func(key, _ any) bool {
+		names = append(names, key.(string))
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+// CreateDbContext creates the DbContext of cfg.DbType with its registered
+// factory. Connection errors and unknown DbTypes are returned as errors.
+func CreateDbContext(cfg *database.DbConfig) (DbContext, error) {
+	if cfg == nil {
+		return nil, errors.New("database: config is required")
+	}
+	factory, ok := dialects.Load(cfg.DbType)
+	if !ok {
+		return nil, fmt.Errorf("database: unsupported db type %q, registered: %v", cfg.DbType, Dialects())
+	}
+	return factory.(DialectFactory)(cfg)
 }
@@ -0,0 +1,53 @@
+package gorm_test
+
+import (
+	"testing"
+
+	database "github.com/loongkirin/gdk/database"
+	gdkgorm "github.com/loongkirin/gdk/database/gorm"
+	_ "github.com/loongkirin/gdk/database/gorm/mysql"
+	_ "github.com/loongkirin/gdk/database/gorm/sqlite"
+)
+
+type widget struct {
+	ID   uint
+	Name string
+}
+
+func TestCreateDbContextSQLite(t *testing.T) {
+	dbContext, err := gdkgorm.CreateDbContext(&database.DbConfig{DbType: gdkgorm.DialectSQLite})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	master := dbContext.GetMasterDb()
+	if err := master.AutoMigrate(&widget{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := master.Create(&widget{Name: "gear"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 内存数据库的读请求落到主库
+	var got widget
+	if err := dbContext.GetSlaveDb().First(&got).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "gear" {
+		t.Errorf("read %+v, want gear", got)
+	}
+}
+
+func TestCreateDbContextErrors(t *testing.T) {
+	if _, err := gdkgorm.CreateDbContext(&database.DbConfig{DbType: "oracle"}); err == nil {
+		t.Error("unknown db type did not fail")
+	}
+
+	cfg := &database.DbConfig{
+		DbType: gdkgorm.DialectMySQL,
+		Master: database.DBConnection{Host: "127.0.0.1", Port: 1, User: "root", DbName: "test"},
+	}
+	if _, err := gdkgorm.CreateDbContext(cfg); err == nil {
+		t.Error("unreachable mysql did not fail")
+	}
+}
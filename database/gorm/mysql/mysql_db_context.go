@@ -0,0 +1,54 @@
+package mysql
+
+import (
+	"fmt"
+
+	database "github.com/loongkirin/gdk/database"
+	gdkgorm "github.com/loongkirin/gdk/database/gorm"
+	"github.com/loongkirin/gdk/database/gorm/replica"
+	gormmysql "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// defaultParams are the DSN parameters used when DBConnection.Config is empty
+const defaultParams = "charset=utf8mb4&parseTime=True&loc=Local"
+
+type MySQLDbContext struct {
+	DbConfig *database.DbConfig
+	*replica.Set
+}
+
+func init() {
+	gdkgorm.RegisterDialect(gdkgorm.DialectMySQL, func(cfg *database.DbConfig) (gdkgorm.DbContext, error) {
+		dbContext, err := NewMySQLDbContext(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return dbContext, nil
+	})
+}
+
+func NewMySQLDbContext(cfg *database.DbConfig) (*MySQLDbContext, error) {
+	set, err := replica.Open(cfg, "mysql", dialector)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MySQLDbContext{
+		DbConfig: cfg,
+		Set:      set,
+	}, nil
+}
+
+func dialector(cfg database.DBConnection) gorm.Dialector {
+	return gormmysql.New(gormmysql.Config{DSN: dsn(cfg)})
+}
+
+// dsn builds a go-sql-driver DSN, DBConnection.Config holds its query parameters
+func dsn(cfg database.DBConnection) string {
+	params := cfg.Config
+	if params == "" {
+		params = defaultParams
+	}
+	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?%s", cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.DbName, params)
+}
@@ -0,0 +1,143 @@
+package replica
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	database "github.com/loongkirin/gdk/database"
+	"github.com/loongkirin/gdk/database/gorm/opentelemetry"
+	"github.com/loongkirin/gdk/util"
+	"gorm.io/gorm"
+	"gorm.io/plugin/opentelemetry/metrics"
+)
+
+// Dialector returns the gorm dialector connecting to one database
+type Dialector func(cfg database.DBConnection) gorm.Dialector
+
+// Set is a master with its read slaves. It holds the connection, tracing and
+// metrics wiring shared by the DbContexts of every dialect.
+type Set struct {
+	master  *gorm.DB
+	slaves  []*gorm.DB
+	lock    sync.Mutex
+	current int
+}
+
+// Open connects to the master and the slaves of cfg with dialector. Tracing
+// and metrics are enabled on every connection according to cfg, dialect names
+// the database in errors and telemetry attributes.
+func Open(cfg *database.DbConfig, dialect string, dialector Dialector) (*Set, error) {
+	master, err := open(cfg, dialect, "master", cfg.Master, dialector)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &Set{master: master}
+	for i, slaveCfg := range cfg.Slaves {
+		slave, err := open(cfg, dialect, fmt.Sprintf("slave_%d", i), slaveCfg, dialector)
+		if err != nil {
+			set.Close()
+			return nil, err
+		}
+		set.slaves = append(set.slaves, slave)
+	}
+	return set, nil
+}
+
+func open(cfg *database.DbConfig, dialect, role string, connCfg database.DBConnection, dialector Dialector) (*gorm.DB, error) {
+	db, err := connectDB(connCfg, dialector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to gorm %s %s: %w", dialect, role, err)
+	}
+
+	if cfg.EnableTracing {
+		tracingPlugin := opentelemetry.NewTracingPlugin(dialect, role, connCfg)
+		if err := db.Use(tracingPlugin); err != nil {
+			closeDB(db)
+			return nil, fmt.Errorf("failed to enable gorm %s %s tracing: %w", dialect, role, err)
+		}
+	}
+
+	if cfg.EnableMetrics {
+		sqlDB, err := db.DB()
+		if err != nil {
+			closeDB(db)
+			return nil, fmt.Errorf("failed to get gorm %s %s sql db: %w", dialect, role, err)
+		}
+
+		opts := opentelemetry.NewMetricsObserverOptions(dialect, role, connCfg)
+		metrics.ReportDBStatsMetrics(sqlDB, opts...)
+	}
+	return db, nil
+}
+
+func connectDB(cfg database.DBConnection, dialector Dialector) (*gorm.DB, error) {
+	db, err := gorm.Open(dialector(cfg), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	if duration, err := util.ParseDuration(cfg.ConnMaxLifetime); err == nil {
+		sqlDB.SetConnMaxLifetime(duration)
+	}
+	return db, nil
+}
+
+func closeDB(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+func (s *Set) GetMasterDb() *gorm.DB {
+	return s.master
+}
+
+// GetSlaveDb returns the slaves in turn, or the master when there is none
+func (s *Set) GetSlaveDb() *gorm.DB {
+	if len(s.slaves) == 0 {
+		return s.master
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.current = (s.current + 1) % len(s.slaves)
+	return s.slaves[s.current]
+}
+
+func (s *Set) HealthCheck() error {
+	// 检查 master
+	if err := s.master.Exec("SELECT 1").Error; err != nil {
+		return fmt.Errorf("master health check failed: %w", err)
+	}
+
+	// 检查 slaves
+	for i, slave := range s.slaves {
+		if err := slave.Exec("SELECT 1").Error; err != nil {
+			return fmt.Errorf("slave_%d health check failed: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Close closes the master and every slave
+func (s *Set) Close() error {
+	var errs []error
+	for _, db := range append([]*gorm.DB{s.master}, s.slaves...) {
+		if err := closeDB(db); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -2,22 +2,65 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
 
 	"github.com/loongkirin/gdk/database/query"
+	gdkrepository "github.com/loongkirin/gdk/database/repository"
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	ErrCursorCodecRequired = errors.New("repository: a cursor codec is required for keyset pagination")
+	ErrInvalidPageSize     = errors.New("repository: page size must be positive")
+	ErrNullableKeyset      = errors.New("repository: keyset pagination cannot order by a nullable column")
 )
 
 type Repository[T any] struct {
-	db *gorm.DB
+	db      *gorm.DB
+	schema  *query.Schema
+	cursors *query.CursorCodec
+	err     error
 }
 
+// Page is a page of a keyset paginated query, see gdkrepository.Page
+type Page[T any] = gdkrepository.Page[T]
+
+var _ gdkrepository.Repository[struct{}] = (*Repository[struct{}])(nil)
+
+// NewRepository creates a Repository whose queries are checked against the
+// schema derived from T with the naming strategy of db, see query.SchemaOf
 func NewRepository[T any](db *gorm.DB) *Repository[T] {
+	schema, err := query.SchemaOf(new(T), db.NamingStrategy)
+	return &Repository[T]{
+		db:     db,
+		schema: schema,
+		err:    err,
+	}
+}
+
+// NewRepositoryWithSchema creates a Repository whose queries are checked
+// against schema
+func NewRepositoryWithSchema[T any](db *gorm.DB, schema *query.Schema) *Repository[T] {
 	return &Repository[T]{
-		db: db,
+		db:     db,
+		schema: schema,
 	}
 }
 
+// WithCursorCodec returns a copy of the Repository signing the cursors of
+// QueryPage with codec
+func (r *Repository[T]) WithCursorCodec(codec *query.CursorCodec) *Repository[T] {
+	clone := *r
+	clone.cursors = codec
+	return &clone
+}
+
 func (r *Repository[T]) Migrate(ctx context.Context, data *T) error {
 	return r.db.WithContext(ctx).AutoMigrate(data)
 }
@@ -34,17 +77,156 @@ func (r *Repository[T]) QueryById(ctx context.Context, id string) (*T, error) {
 	return data, nil
 }
 
+// Query returns the page of query selected by PageNumber, with one more row
+// than PageSize when a next page exists. Use QueryPage for deep pages of large
+// tables.
 func (r *Repository[T]) Query(ctx context.Context, query *query.DbQuery) ([]T, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
 	datas := []T{}
-	whereClaues, values, order := query.GetWhereClause()
+	whereClaues, values, order, err := query.BuildFor(r.schema, r.db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
 	offset := (query.PageNumber - 1) * query.PageSize
-	err := r.db.WithContext(ctx).Where(whereClaues, values...).Order(order).Offset(offset).Limit(query.PageSize + 1).Find(&datas).Error
+	err = r.db.WithContext(ctx).Where(whereClaues, values...).Order(order).Offset(offset).Limit(query.PageSize + 1).Find(&datas).Error
 	if err != nil {
 		return nil, err
 	}
 	return datas, nil
 }
 
+// QueryPage returns the page of q after or before q.Cursor, the first page
+// when it is empty. Rows are sorted by q.OrderBy then by the primary key, and
+// the page is selected by comparing these columns with the row of the cursor
+// instead of skipping rows, so it does not slow down on deep pages or shift
+// under concurrent inserts. NULL never compares, so ErrNullableKeyset is
+// returned when a column of q.OrderBy may be NULL: a pointer field or one
+// without a not null tag, unless its query.Field is declared Keyset.
+func (r *Repository[T]) QueryPage(ctx context.Context, q *query.DbQuery) (*Page[T], error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.cursors == nil {
+		return nil, ErrCursorCodecRequired
+	}
+	if q.PageSize < 1 {
+		return nil, ErrInvalidPageSize
+	}
+	whereClaues, values, _, err := q.BuildFor(r.schema, r.db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	stmt := &gorm.Statement{DB: r.db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, err
+	}
+	if stmt.Schema.PrioritizedPrimaryField == nil {
+		return nil, gorm.ErrPrimaryKeyRequired
+	}
+	columns := q.KeysetColumns(r.schema, stmt.Schema.PrioritizedPrimaryField.DBName)
+	fields := make([]*schema.Field, len(columns))
+	for i, column := range columns {
+		if fields[i] = lookUpField(stmt.Schema, column.Column); fields[i] == nil {
+			return nil, gorm.ErrInvalidField
+		}
+		if !column.NotNull && nullable(fields[i]) {
+			return nil, fmt.Errorf("%w: %s", ErrNullableKeyset, column.Column)
+		}
+	}
+
+	db := r.db.WithContext(ctx).Where(whereClaues, values...)
+	var cursor *query.Cursor
+	backward := false
+	if q.Cursor != "" {
+		if cursor, err = r.cursors.Decode(q.Cursor); err != nil {
+			return nil, err
+		}
+		if cursor.Order != query.KeysetSignature(columns) || len(cursor.Values) != len(columns) {
+			return nil, query.ErrInvalidCursor
+		}
+		// 游标中的值按字段类型解码，时间等类型才能与列正确比较
+		positions := make([]interface{}, len(columns))
+		for i, raw := range cursor.Values {
+			value := reflect.New(fields[i].FieldType)
+			if err := json.Unmarshal(raw, value.Interface()); err != nil {
+				return nil, query.ErrInvalidCursor
+			}
+			positions[i] = value.Elem().Interface()
+		}
+		backward = cursor.Backward
+		keysetClause, keysetValues := query.KeysetWhere(columns, positions, backward)
+		db = db.Where(keysetClause, keysetValues...)
+	}
+
+	datas := []T{}
+	err = db.Order(query.KeysetOrder(columns, backward)).Limit(q.PageSize + 1).Find(&datas).Error
+	if err != nil {
+		return nil, err
+	}
+	more := len(datas) > q.PageSize
+	if more {
+		datas = datas[:q.PageSize]
+	}
+	if backward {
+		slices.Reverse(datas)
+	}
+
+	page := &Page[T]{DataList: datas}
+	if len(datas) == 0 {
+		return page, nil
+	}
+	// 向后翻页时下一页必然存在，向前翻页时上一页必然存在
+	if more || backward {
+		if page.NextCursor, err = r.encodeCursor(ctx, fields, columns, &datas[len(datas)-1], false); err != nil {
+			return nil, err
+		}
+	}
+	if (more && backward) || (cursor != nil && !backward) {
+		if page.PrevCursor, err = r.encodeCursor(ctx, fields, columns, &datas[0], true); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// encodeCursor returns the cursor of the page after data, or before it when
+// backward
+func (r *Repository[T]) encodeCursor(ctx context.Context, fields []*schema.Field, columns []query.KeysetColumn, data *T, backward bool) (string, error) {
+	cursor := &query.Cursor{
+		Values:   make([]json.RawMessage, len(fields)),
+		Order:    query.KeysetSignature(columns),
+		Backward: backward,
+	}
+	for i, field := range fields {
+		value, _ := field.ValueOf(ctx, reflect.ValueOf(data).Elem())
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		cursor.Values[i] = raw
+	}
+	return r.cursors.Encode(cursor)
+}
+
+// nullable reports whether the column of field may hold NULL
+func nullable(field *schema.Field) bool {
+	if field.PrimaryKey {
+		return false
+	}
+	return field.FieldType.Kind() == reflect.Ptr || !field.NotNull
+}
+
+// lookUpField returns the field of a column, which may be qualified by a
+// table name
+func lookUpField(s *schema.Schema, column string) *schema.Field {
+	if i := strings.LastIndex(column, "."); i >= 0 {
+		column = column[i+1:]
+	}
+	return s.LookUpField(column)
+}
+
 func (r *Repository[T]) Add(ctx context.Context, data *T) (*T, error) {
 	err := r.db.WithContext(ctx).Create(data).Error
 	if err != nil {
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/loongkirin/gdk/database/query"
+	gdkrepository "github.com/loongkirin/gdk/database/repository"
+	"github.com/loongkirin/gdk/net/http/response"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+type item struct {
+	Id    string  `gorm:"primaryKey" json:"id"`
+	Score int     `gorm:"not null" json:"score"`
+	Note  *string `json:"note"`
+	Rank  int     `json:"rank"`
+	Level int     `json:"level" query:"keyset"`
+}
+
+func newItemRepository(t *testing.T) *Repository[item] {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&item{}); err != nil {
+		t.Fatal(err)
+	}
+	// 分数重复，依赖主键保证顺序唯一
+	for i := 0; i < 7; i++ {
+		if err := db.Create(&item{Id: fmt.Sprintf("i%d", i), Score: i / 2}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	codec, err := query.NewCursorCodec([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewRepository[item](db).WithCursorCodec(codec)
+}
+
+func ids(items []item) string {
+	s := ""
+	for _, item := range items {
+		s += item.Id + " "
+	}
+	return s
+}
+
+func TestRepositoryQueryPage(t *testing.T) {
+	ctx := context.Background()
+	r := newItemRepository(t)
+	order := []query.DbQueryOrderBy{query.NewDDbQueryOrderBy("score", false)}
+	q := query.NewDbQuery(nil, 3, 1, order)
+
+	// 通过接口翻页，并转换为响应中的分页信息
+	var repo gdkrepository.Repository[item] = r
+	var pages []string
+	var last *Page[item]
+	for {
+		page, err := repo.QueryPage(ctx, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := response.NewCursorListResponse(page.DataList, q.PageSize, page.NextCursor, page.PrevCursor)
+		if resp.Pagination.NextCursor != page.NextCursor || resp.Pagination.PrevCursor != page.PrevCursor || resp.Pagination.HasNextPage != (page.NextCursor != "") {
+			t.Fatalf("pagination = %+v, page = %+v", resp.Pagination, page)
+		}
+		pages = append(pages, ids(resp.DataList))
+		last = page
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+	want := []string{"i6 i4 i5 ", "i2 i3 i0 ", "i1 "}
+	if fmt.Sprint(pages) != fmt.Sprint(want) {
+		t.Fatalf("pages = %q, want %q", pages, want)
+	}
+
+	// 从最后一页向前翻
+	q.Cursor = last.PrevCursor
+	page, err := r.QueryPage(ctx, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(page.DataList); got != want[1] || page.NextCursor == "" || page.PrevCursor == "" {
+		t.Fatalf("previous page = %q, %+v", got, page)
+	}
+	q.Cursor = page.PrevCursor
+	if page, err = r.QueryPage(ctx, q); err != nil || ids(page.DataList) != want[0] || page.PrevCursor != "" {
+		t.Fatalf("first page = %+v, %v", page, err)
+	}
+
+	q.Cursor = "x" + last.PrevCursor
+	if _, err := r.QueryPage(ctx, q); !errors.Is(err, query.ErrInvalidCursor) {
+		t.Errorf("tampered cursor error = %v", err)
+	}
+	q.Cursor, q.OrderBy = last.PrevCursor, nil
+	if _, err := r.QueryPage(ctx, q); !errors.Is(err, query.ErrInvalidCursor) {
+		t.Errorf("cursor of another order error = %v", err)
+	}
+	q.Cursor, q.OrderBy = "", []query.DbQueryOrderBy{query.NewDDbQueryOrderBy("note", true)}
+	if _, err := r.QueryPage(ctx, q); !errors.Is(err, ErrNullableKeyset) {
+		t.Errorf("nullable order error = %v", err)
+	}
+
+	// 没有 not null 标签的列需要声明为 keyset 才能用于游标分页
+	q.OrderBy = []query.DbQueryOrderBy{query.NewDDbQueryOrderBy("rank", true)}
+	if _, err := r.QueryPage(ctx, q); !errors.Is(err, ErrNullableKeyset) {
+		t.Errorf("undeclared order error = %v", err)
+	}
+	q.OrderBy = []query.DbQueryOrderBy{query.NewDDbQueryOrderBy("level", true)}
+	if page, err := r.QueryPage(ctx, q); err != nil || len(page.DataList) != 3 {
+		t.Errorf("keyset tag page = %+v, %v", page, err)
+	}
+	itemSchema, err := query.SchemaOf(&item{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := itemSchema.SetKeyset("rank"); err != nil {
+		t.Fatal(err)
+	}
+	q.OrderBy = []query.DbQueryOrderBy{query.NewDDbQueryOrderBy("rank", true)}
+	if page, err := NewRepositoryWithSchema[item](r.db, itemSchema).WithCursorCodec(r.cursors).QueryPage(ctx, q); err != nil || len(page.DataList) != 3 {
+		t.Errorf("SetKeyset page = %+v, %v", page, err)
+	}
+}
+
+type ranked struct {
+	ID    string `gorm:"primaryKey"`
+	Order int    `gorm:"not null"`
+}
+
+func TestRepositoryNamingStrategy(t *testing.T) {
+	ctx := context.Background()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		NamingStrategy: schema.NamingStrategy{NoLowerCase: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&ranked{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := db.Create(&ranked{ID: fmt.Sprint(i), Order: -i}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	codec, err := query.NewCursorCodec([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRepository[ranked](db).WithCursorCodec(codec)
+
+	// 列名按 NoLowerCase 命名，且 Order 是保留字
+	q := query.NewDbQuery([]query.DbQueryWhere{
+		query.NewDbQueryWhere([]query.DbQueryFilter{query.NewDbQueryFilter("Order", []interface{}{-2}, query.GT, "")}, query.AND),
+	}, 10, 1, []query.DbQueryOrderBy{query.NewDDbQueryOrderBy("Order", true)})
+	datas, err := r.Query(ctx, q)
+	if err != nil || len(datas) != 2 || datas[0].ID != "1" {
+		t.Fatalf("Query = %+v, %v", datas, err)
+	}
+	q.PageSize = 1
+	page, err := r.QueryPage(ctx, q)
+	if err != nil || len(page.DataList) != 1 || page.DataList[0].ID != "1" || page.NextCursor == "" {
+		t.Fatalf("QueryPage = %+v, %v", page, err)
+	}
+}
@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	database "github.com/loongkirin/gdk/database"
+	gdkgorm "github.com/loongkirin/gdk/database/gorm"
+	"github.com/loongkirin/gdk/database/gorm/replica"
+	gormsqlite "gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// MemoryDbName opens a private in-memory database, handy for tests
+const MemoryDbName = ":memory:"
+
+// SQLiteDbContext is a DbContext on a SQLite file. DBConnection.DbName is the
+// file path and DBConnection.Config its query parameters, the host, port and
+// credentials are ignored. An empty DbName or MemoryDbName opens a private
+// in-memory database, its slaves are ignored and reads go to the master.
+type SQLiteDbContext struct {
+	DbConfig *database.DbConfig
+	*replica.Set
+}
+
+func init() {
+	gdkgorm.RegisterDialect(gdkgorm.DialectSQLite, func(cfg *database.DbConfig) (gdkgorm.DbContext, error) {
+		dbContext, err := NewSQLiteDbContext(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return dbContext, nil
+	})
+}
+
+func NewSQLiteDbContext(cfg *database.DbConfig) (*SQLiteDbContext, error) {
+	// 内存数据库只存在于单个连接中，连接池必须固定为一个连接，且没有从库
+	openCfg := *cfg
+	if isMemory(cfg.Master) {
+		openCfg.Master = memoryConnection(cfg.Master)
+		openCfg.Slaves = nil
+	}
+
+	set, err := replica.Open(&openCfg, "sqlite", dialector)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SQLiteDbContext{
+		DbConfig: cfg,
+		Set:      set,
+	}, nil
+}
+
+func isMemory(cfg database.DBConnection) bool {
+	return cfg.DbName == "" || cfg.DbName == MemoryDbName
+}
+
+// memoryConnection pins the pool of an in-memory database to one connection
+// that is never closed
+func memoryConnection(cfg database.DBConnection) database.DBConnection {
+	cfg.DbName = MemoryDbName
+	cfg.MaxOpenConns = 1
+	cfg.MaxIdleConns = 1
+	cfg.ConnMaxLifetime = ""
+	return cfg
+}
+
+func dialector(cfg database.DBConnection) gorm.Dialector {
+	dsn := cfg.DbName
+	if cfg.Config != "" {
+		dsn += "?" + cfg.Config
+	}
+	return gormsqlite.Open(dsn)
+}
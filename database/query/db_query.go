@@ -3,6 +3,8 @@ package query
 import (
 	"fmt"
 	"strings"
+
+	"gorm.io/gorm/clause"
 )
 
 type Connector string
@@ -24,13 +26,34 @@ const (
 	LIKE    FilterOperation = "LIKE"
 	IN      FilterOperation = "IN"
 	BETWEEN FilterOperation = "BETWEEN"
+
+	NOT_IN      FilterOperation = "NOT_IN"
+	IS_NULL     FilterOperation = "IS_NULL"
+	NOT_NULL    FilterOperation = "NOT_NULL"
+	STARTS_WITH FilterOperation = "STARTS_WITH"
+	ENDS_WITH   FilterOperation = "ENDS_WITH"
+	// ILIKE is a case-insensitive LIKE
+	ILIKE FilterOperation = "ILIKE"
+	// JSON_CONTAINS matches JSON documents containing the value
+	JSON_CONTAINS FilterOperation = "JSON_CONTAINS"
+	// JSON_PATH matches JSON documents where the path exists, a plain path of
+	// keys and indexes like $.a[0].b
+	JSON_PATH FilterOperation = "JSON_PATH"
+	// SEARCH is a full-text search, on a tsvector or text column in Postgres
+	// and on a FULLTEXT index in MySQL
+	SEARCH FilterOperation = "SEARCH"
 )
 
+// DbQuery filters with the conditions of QueryWheres and Where, which are
+// combined with AND when both are set. Pages are selected by PageNumber, or
+// by Cursor for keyset pagination.
 type DbQuery struct {
 	QueryWheres []DbQueryWhere   `json:"query_wheres"`
+	Where       *DbQueryExpr     `json:"where,omitempty"`
 	OrderBy     []DbQueryOrderBy `json:"order_by"`
 	PageSize    int              `json:"page_size"`
 	PageNumber  int              `json:"page_number"`
+	Cursor      string           `json:"cursor,omitempty"`
 }
 
 func NewDbQuery(wheres []DbQueryWhere, ps int, pn int, order []DbQueryOrderBy) *DbQuery {
@@ -42,62 +65,103 @@ func NewDbQuery(wheres []DbQueryWhere, ps int, pn int, order []DbQueryOrderBy) *
 	}
 }
 
+// NewDbQueryExpr creates a DbQuery filtering with an expression tree
+func NewDbQueryExpr(where *DbQueryExpr, ps int, pn int, order []DbQueryOrderBy) *DbQuery {
+	return &DbQuery{
+		Where:      where,
+		PageSize:   ps,
+		PageNumber: pn,
+		OrderBy:    order,
+	}
+}
+
+// Expr returns the conditions of q as a single tree, nil when q has none.
+// QueryWheres are converted with the precedence of SQL, AND binding tighter
+// than OR, and the connector of the last filter or where is ignored.
+func (q *DbQuery) Expr() *DbQueryExpr {
+	wheres := exprOfWheres(q.QueryWheres)
+	switch {
+	case wheres == nil:
+		return q.Where
+	case q.Where == nil:
+		return wheres
+	}
+	return And(wheres, q.Where)
+}
+
+// Build is BuildFor with the DefaultDialect
+func (q *DbQuery) Build(schema *Schema) (whereClause string, values []interface{}, order clause.OrderBy, err error) {
+	return q.BuildFor(schema, DefaultDialect)
+}
+
+// BuildFor validates q against schema, then returns its where clause, values
+// and order for dialect with the public field names mapped to columns. A
+// *FieldError is returned for any field, operation or connector the schema
+// does not allow, ErrUnsupportedOperation for an operation the dialect lacks.
+// Columns are passed as clause.Column values, so GORM quotes them for the
+// dialect of the connection. The order defaults to the primary key of schema.
+func (q *DbQuery) BuildFor(schema *Schema, dialect string) (whereClause string, values []interface{}, order clause.OrderBy, err error) {
+	if err := schema.Validate(q); err != nil {
+		return "", nil, order, err
+	}
+	whereClause, values, err = q.render(dialect, schemaColumn(schema))
+	if err != nil {
+		return "", nil, order, err
+	}
+	for _, o := range q.OrderBy {
+		order.Columns = append(order.Columns, clause.OrderByColumn{
+			Column: clause.Column{Name: schema.fields[o.FieldName].Column},
+			Desc:   !o.IsAsc,
+		})
+	}
+	if len(order.Columns) == 0 {
+		order.Columns = []clause.OrderByColumn{{Column: clause.Column{Name: schema.primaryKey}}}
+	}
+	return whereClause, values, order, nil
+}
+
+// GetWhereClause returns the where clause, values and order of q.
+//
+// Deprecated: field names are written into the SQL as they are, use Build
+// with a Schema for queries coming from clients.
 func (q *DbQuery) GetWhereClause() (whereClause string, values []interface{}, order string) {
-	whereClause = " 1=1 "
-	order = q.GetOrderBy()
-	if len(q.QueryWheres) < 1 {
-		return whereClause, values, order
+	whereClause, values, err := q.render(DefaultDialect, rawColumn)
+	if err != nil {
+		// 无法渲染的条件不匹配任何数据，而不是全部数据
+		return "1=0", nil, q.GetOrderBy()
 	}
-	whereClause += "AND "
-	var sb strings.Builder
+	return whereClause, values, q.GetOrderBy()
+}
 
+func (q *DbQuery) render(dialect string, column func(name string) (string, []interface{})) (string, []interface{}, error) {
+	expr := q.Expr()
+	if expr == nil {
+		return "1=1", nil, nil
+	}
+	// 渲染前检查树的结构和值的个数，Build 之外的调用未经 Schema 校验
+	check := func(filter DbQueryFilter) error {
+		if !validValues(filter) {
+			return &FieldError{Err: ErrInvalidValues, Field: filter.FieldName, Operation: filter.FilterOperation}
+		}
+		return nil
+	}
 	for _, where := range q.QueryWheres {
-		var subClause strings.Builder
 		for _, filter := range where.QueryFilters {
-			fieldName := filter.FieldName
-			var op string
-			switch filter.FilterOperation {
-			case EQ:
-				op = " = ? "
-				values = append(values, filter.FilterValues[0])
-			case NEQ:
-				op = " <> ? "
-				values = append(values, filter.FilterValues[0])
-			case LT:
-				op = " < ? "
-				values = append(values, filter.FilterValues[0])
-			case LTE:
-				op = " <= ? "
-				values = append(values, filter.FilterValues[0])
-			case GT:
-				op = " > ? "
-				values = append(values, filter.FilterValues[0])
-			case GTE:
-				op = " >= ? "
-				values = append(values, filter.FilterValues[0])
-			case LIKE:
-				op = " LIKE ? "
-				values = append(values, "%"+fmt.Sprint(filter.FilterValues[0])+"%")
-			case IN:
-				op = " IN ? "
-				values = append(values, filter.FilterValues)
-			case BETWEEN:
-				op = " BETWEEN ? AND ? "
-				values = append(values, filter.FilterValues[0], filter.FilterValues[1])
+			if err := check(filter); err != nil {
+				return "", nil, err
 			}
-			subClause.WriteString(fmt.Sprintf(" %s %s %s ", fieldName, op, filter.Connector))
 		}
-		subWhere := subClause.String()
-		subWhere = strings.Trim(subWhere, "AND ")
-		subWhere = strings.Trim(subWhere, "OR ")
-		sb.WriteString(fmt.Sprintf(" (%s) ", subWhere))
-		sb.WriteString(fmt.Sprintf(" %s ", where.Connector))
 	}
-	whereClause = whereClause + sb.String()
-	whereClause = strings.Trim(whereClause, "AND ")
-	whereClause = strings.Trim(whereClause, "OR ")
-
-	return whereClause, values, order
+	if q.Where != nil {
+		if err := q.Where.walk(1, check); err != nil {
+			return "", nil, err
+		}
+	}
+	r := &renderer{dialect: dialect, column: column}
+	if err := r.render(expr); err != nil {
+		return "", nil, err
+	}
+	return r.sb.String(), r.vars, nil
 }
 
 func (q *DbQuery) GetOrderBy() string {
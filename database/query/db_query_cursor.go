@@ -0,0 +1,160 @@
+package query
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm/clause"
+)
+
+// MinCursorKeySize is the minimum size of the key signing cursors
+const MinCursorKeySize = 32
+
+var ErrInvalidCursor = errors.New("query: invalid cursor")
+
+// Cursor is the position of a keyset page, the order values of the row a
+// page starts after
+type Cursor struct {
+	// Values are the JSON encoded values of the keyset columns
+	Values []json.RawMessage `json:"v"`
+	// Order is the signature of the keyset columns the cursor was made for
+	Order string `json:"o"`
+	// Backward reads the rows before the position instead of after it
+	Backward bool `json:"b,omitempty"`
+}
+
+// CursorCodec encodes cursors as opaque tokens signed with HMAC-SHA256, so
+// clients can pass them back but not forge them
+type CursorCodec struct {
+	key []byte
+}
+
+func NewCursorCodec(key []byte) (*CursorCodec, error) {
+	if len(key) < MinCursorKeySize {
+		return nil, fmt.Errorf("invalid cursor key size: must be at least %d bytes", MinCursorKeySize)
+	}
+	return &CursorCodec{key: key}, nil
+}
+
+func (c *CursorCodec) sign(payload string) string {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Encode returns the token of cursor
+func (c *CursorCodec) Encode(cursor *Cursor) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + c.sign(payload), nil
+}
+
+// Decode verifies a token returned by Encode and returns its cursor
+func (c *CursorCodec) Decode(token string) (*Cursor, error) {
+	payload, signature, found := strings.Cut(token, ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(c.sign(payload))) {
+		return nil, ErrInvalidCursor
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	cursor := &Cursor{}
+	if err := json.Unmarshal(data, cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// KeysetColumn is a column of the order of a keyset page
+type KeysetColumn struct {
+	Column string
+	Desc   bool
+	// NotNull is set for the primary key and the columns of Keyset fields
+	NotNull bool
+}
+
+// KeysetColumns returns the order of q with the public field names mapped to
+// columns, followed by primaryKey so the order is unique. q must be valid for
+// schema, see Build.
+func (q *DbQuery) KeysetColumns(schema *Schema, primaryKey string) []KeysetColumn {
+	columns := make([]KeysetColumn, 0, len(q.OrderBy)+1)
+	unique := false
+	for _, order := range q.OrderBy {
+		field := schema.fields[order.FieldName]
+		column := field.Column
+		columns = append(columns, KeysetColumn{Column: column, Desc: !order.IsAsc, NotNull: field.Keyset || column == primaryKey})
+		unique = unique || column == primaryKey
+	}
+	if !unique {
+		columns = append(columns, KeysetColumn{Column: primaryKey, NotNull: true})
+	}
+	return columns
+}
+
+// KeysetSignature identifies the order of columns, a cursor made for another
+// order is rejected
+func KeysetSignature(columns []KeysetColumn) string {
+	var sb strings.Builder
+	for i, column := range columns {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(column.Column)
+		if column.Desc {
+			sb.WriteString(" DESC")
+		}
+	}
+	return sb.String()
+}
+
+// KeysetWhere returns the condition matching the rows after values in the
+// order of columns, or before them when backward. The columns must not be
+// NULL, which never compares.
+//
+//	(a > ? OR (a = ? AND id > ?))
+func KeysetWhere(columns []KeysetColumn, values []interface{}, backward bool) (string, []interface{}) {
+	var sb strings.Builder
+	var vars []interface{}
+	sb.WriteString("(")
+	for i, column := range columns {
+		if i > 0 {
+			sb.WriteString(" OR (")
+		}
+		for j := 0; j < i; j++ {
+			sb.WriteString("? = ? AND ")
+			vars = append(vars, clause.Column{Name: columns[j].Column}, values[j])
+		}
+		if column.Desc != backward {
+			sb.WriteString("? < ?")
+		} else {
+			sb.WriteString("? > ?")
+		}
+		vars = append(vars, clause.Column{Name: column.Column}, values[i])
+		if i > 0 {
+			sb.WriteString(")")
+		}
+	}
+	sb.WriteString(")")
+	return sb.String(), vars
+}
+
+// KeysetOrder returns the order of columns, reversed when backward
+func KeysetOrder(columns []KeysetColumn, backward bool) clause.OrderBy {
+	order := clause.OrderBy{Columns: make([]clause.OrderByColumn, len(columns))}
+	for i, column := range columns {
+		order.Columns[i] = clause.OrderByColumn{
+			Column: clause.Column{Name: column.Column},
+			Desc:   column.Desc != backward,
+		}
+	}
+	return order
+}
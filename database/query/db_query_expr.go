@@ -0,0 +1,183 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm/clause"
+)
+
+// NOT negates the single operand of a DbQueryExpr
+const NOT Connector = "NOT"
+
+// maxExprDepth bounds the nesting of the expressions accepted from clients
+const maxExprDepth = 32
+
+var ErrInvalidExpr = errors.New("query: invalid expression")
+
+// DbQueryExpr is a node of a boolean filter tree. A leaf holds a Filter, an
+// inner node combines its Exprs with AND or OR, or negates its single operand
+// with NOT:
+//
+//	{"operator": "OR", "exprs": [
+//		{"filter": {"field_name": "status", "filter_values": [1], "filter_operation": "EQ"}},
+//		{"operator": "NOT", "exprs": [{"filter": {...}}]}
+//	]}
+type DbQueryExpr struct {
+	Operator Connector      `json:"operator,omitempty"`
+	Exprs    []*DbQueryExpr `json:"exprs,omitempty"`
+	Filter   *DbQueryFilter `json:"filter,omitempty"`
+}
+
+// FilterExpr returns a leaf holding filter
+func FilterExpr(filter DbQueryFilter) *DbQueryExpr {
+	return &DbQueryExpr{Filter: &filter}
+}
+
+// And returns a node matching when every expression matches
+func And(exprs ...*DbQueryExpr) *DbQueryExpr {
+	return &DbQueryExpr{Operator: AND, Exprs: exprs}
+}
+
+// Or returns a node matching when any expression matches
+func Or(exprs ...*DbQueryExpr) *DbQueryExpr {
+	return &DbQueryExpr{Operator: OR, Exprs: exprs}
+}
+
+// Not returns a node matching when expr does not match
+func Not(expr *DbQueryExpr) *DbQueryExpr {
+	return &DbQueryExpr{Operator: NOT, Exprs: []*DbQueryExpr{expr}}
+}
+
+// walk checks the shape of the tree and calls fn for every filter
+func (e *DbQueryExpr) walk(depth int, fn func(filter DbQueryFilter) error) error {
+	if e == nil {
+		return fmt.Errorf("%w: empty node", ErrInvalidExpr)
+	}
+	if depth > maxExprDepth {
+		return fmt.Errorf("%w: nested deeper than %d", ErrInvalidExpr, maxExprDepth)
+	}
+
+	if e.Filter != nil {
+		if e.Operator != "" || len(e.Exprs) > 0 {
+			return fmt.Errorf("%w: a filter node cannot have operands", ErrInvalidExpr)
+		}
+		return fn(*e.Filter)
+	}
+	switch e.Operator {
+	case AND, OR:
+		if len(e.Exprs) == 0 {
+			return fmt.Errorf("%w: %s without operands", ErrInvalidExpr, e.Operator)
+		}
+	case NOT:
+		if len(e.Exprs) != 1 {
+			return fmt.Errorf("%w: NOT takes exactly one operand", ErrInvalidExpr)
+		}
+	default:
+		return fmt.Errorf("%w: unknown operator %q", ErrInvalidExpr, e.Operator)
+	}
+	for _, expr := range e.Exprs {
+		if err := expr.walk(depth+1, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chain combines items joined by connectors, connectors[i] joining items[i]
+// and items[i+1]. AND binds tighter than OR like in SQL, an empty connector
+// means AND.
+func chain(items []*DbQueryExpr, connectors []Connector) *DbQueryExpr {
+	var ors []*DbQueryExpr
+	current := []*DbQueryExpr{items[0]}
+	for i := 1; i < len(items); i++ {
+		if connectors[i-1] == OR {
+			ors = append(ors, collapse(AND, current))
+			current = nil
+		}
+		current = append(current, items[i])
+	}
+	ors = append(ors, collapse(AND, current))
+	return collapse(OR, ors)
+}
+
+func collapse(operator Connector, exprs []*DbQueryExpr) *DbQueryExpr {
+	if len(exprs) == 1 {
+		return exprs[0]
+	}
+	return &DbQueryExpr{Operator: operator, Exprs: exprs}
+}
+
+// exprOfWheres converts the two levels of query_wheres to a tree
+func exprOfWheres(wheres []DbQueryWhere) *DbQueryExpr {
+	var groups []*DbQueryExpr
+	var groupConnectors []Connector
+	for _, where := range wheres {
+		if len(where.QueryFilters) == 0 {
+			continue
+		}
+		filters := make([]*DbQueryExpr, len(where.QueryFilters))
+		connectors := make([]Connector, len(where.QueryFilters))
+		for i, filter := range where.QueryFilters {
+			filters[i] = FilterExpr(filter)
+			connectors[i] = filter.Connector
+		}
+		groups = append(groups, chain(filters, connectors))
+		groupConnectors = append(groupConnectors, where.Connector)
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+	return chain(groups, groupConnectors)
+}
+
+// renderer writes a tree as a parameterized SQL condition. Columns are
+// resolved by column, which returns what to write and the vars it consumes.
+type renderer struct {
+	sb      strings.Builder
+	vars    []interface{}
+	dialect string
+	column  func(name string) (string, []interface{})
+}
+
+// rawColumn writes field names into the SQL as they are
+func rawColumn(name string) (string, []interface{}) {
+	return name, nil
+}
+
+// schemaColumn passes the column of a field as a var, so GORM quotes it for
+// the dialect of the connection
+func schemaColumn(schema *Schema) func(name string) (string, []interface{}) {
+	return func(name string) (string, []interface{}) {
+		return "?", []interface{}{clause.Column{Name: schema.fields[name].Column}}
+	}
+}
+
+func (r *renderer) render(e *DbQueryExpr) error {
+	if e.Filter != nil {
+		return r.renderFilter(*e.Filter)
+	}
+	if e.Operator == NOT {
+		r.sb.WriteString("NOT (")
+		if err := r.render(e.Exprs[0]); err != nil {
+			return err
+		}
+		r.sb.WriteString(")")
+		return nil
+	}
+	if len(e.Exprs) == 1 {
+		return r.render(e.Exprs[0])
+	}
+	r.sb.WriteString("(")
+	for i, expr := range e.Exprs {
+		if i > 0 {
+			r.sb.WriteString(" " + string(e.Operator) + " ")
+		}
+		if err := r.render(expr); err != nil {
+			return err
+		}
+	}
+	r.sb.WriteString(")")
+	return nil
+}
@@ -0,0 +1,215 @@
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	database "github.com/loongkirin/gdk/database"
+	gdksqlite "github.com/loongkirin/gdk/database/gorm/sqlite"
+)
+
+func TestDbQueryWhereClause(t *testing.T) {
+	tests := []struct {
+		name   string
+		q      *DbQuery
+		where  string
+		values []interface{}
+	}{
+		{
+			name:  "empty",
+			q:     NewDbQuery(nil, 10, 1, nil),
+			where: "1=1",
+		},
+		{
+			// AND binds tighter than OR, trailing connectors are ignored and
+			// names made of connector letters are kept
+			name: "query_wheres",
+			q: NewDbQuery([]DbQueryWhere{
+				NewDbQueryWhere([]DbQueryFilter{
+					{FieldName: "ROLE", FilterValues: []interface{}{"a"}, FilterOperation: EQ, Connector: AND},
+					{FieldName: "AND_D", FilterValues: []interface{}{1}, FilterOperation: GT, Connector: OR},
+					{FieldName: "x", FilterValues: []interface{}{2}, FilterOperation: LT, Connector: OR},
+				}, AND),
+				NewDbQueryWhere([]DbQueryFilter{
+					NewDbQueryFilter("y", []interface{}{1, 2}, IN, ""),
+				}, OR),
+			}, 10, 1, nil),
+			where:  "(((ROLE = ? AND AND_D > ?) OR x < ?) AND y IN ?)",
+			values: []interface{}{"a", 1, 2, []interface{}{1, 2}},
+		},
+		{
+			name: "nested",
+			q: NewDbQueryExpr(Or(
+				And(
+					FilterExpr(NewDbQueryFilter("a", []interface{}{1}, EQ, "")),
+					Not(FilterExpr(NewDbQueryFilter("b", []interface{}{"x"}, LIKE, ""))),
+				),
+				FilterExpr(NewDbQueryFilter("c", []interface{}{1, 5}, BETWEEN, "")),
+			), 10, 1, nil),
+			where:  "((a = ? AND NOT (b LIKE ? ESCAPE '!')) OR c BETWEEN ? AND ?)",
+			values: []interface{}{1, "%x%", 1, 5},
+		},
+	}
+	for _, tt := range tests {
+		where, values, _ := tt.q.GetWhereClause()
+		if where != tt.where || !reflect.DeepEqual(values, tt.values) {
+			t.Errorf("%s: got %q %v, want %q %v", tt.name, where, values, tt.where, tt.values)
+		}
+	}
+}
+
+func TestDbQueryWhereClauseInvalid(t *testing.T) {
+	tests := []*DbQuery{
+		NewDbQueryExpr(&DbQueryExpr{Operator: NOT}, 10, 1, nil),
+		NewDbQueryExpr(And(nil, nil), 10, 1, nil),
+		NewDbQueryExpr(FilterExpr(DbQueryFilter{FieldName: "a", FilterOperation: EQ}), 10, 1, nil),
+		NewDbQueryExpr(&DbQueryExpr{}, 10, 1, nil),
+		NewDbQuery([]DbQueryWhere{NewDbQueryWhere([]DbQueryFilter{NewDbQueryFilter("a", nil, BETWEEN, "")}, AND)}, 10, 1, nil),
+	}
+	for i, q := range tests {
+		if where, values, _ := q.GetWhereClause(); where != "1=0" || values != nil {
+			t.Errorf("%d: got %q %v, want 1=0", i, where, values)
+		}
+	}
+}
+
+func TestDbQueryExprJSON(t *testing.T) {
+	var q DbQuery
+	err := json.Unmarshal([]byte(`{
+		"query_wheres": [{"query_filters": [{"field_name": "balance", "filter_values": [1], "filter_operation": "GT"}]}],
+		"where": {"operator": "NOT", "exprs": [{"filter": {"field_name": "userName", "filter_values": ["bob"], "filter_operation": "EQ"}}]}
+	}`), &q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := SchemaOf(&account{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	where, values, _, err := q.Build(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if where != "(? > ? AND NOT (? = ?))" || len(values) != 4 {
+		t.Errorf("where = %q, values = %v", where, values)
+	}
+
+	invalid := []*DbQueryExpr{
+		{Operator: AND},
+		{Operator: NOT, Exprs: []*DbQueryExpr{FilterExpr(NewDbQueryFilter("id", []interface{}{1}, EQ, "")), nil}},
+		{Operator: "XOR", Exprs: []*DbQueryExpr{FilterExpr(NewDbQueryFilter("id", []interface{}{1}, EQ, ""))}},
+	}
+	deep := FilterExpr(NewDbQueryFilter("id", []interface{}{1}, EQ, ""))
+	for i := 0; i < maxExprDepth; i++ {
+		deep = Not(deep)
+	}
+	invalid = append(invalid, deep)
+	for _, expr := range invalid {
+		if _, _, _, err := NewDbQueryExpr(expr, 10, 1, nil).Build(schema); !errors.Is(err, ErrInvalidExpr) {
+			t.Errorf("Build(%+v) error = %v, want ErrInvalidExpr", expr, err)
+		}
+	}
+	if _, _, _, err := NewDbQueryExpr(Not(FilterExpr(NewDbQueryFilter("token", []interface{}{1}, EQ, ""))), 10, 1, nil).Build(schema); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("Build of an unknown field in a tree error = %v", err)
+	}
+}
+
+func TestDbQueryOperations(t *testing.T) {
+	tests := []struct {
+		dialect string
+		filter  DbQueryFilter
+		where   string
+		values  []interface{}
+		err     error
+	}{
+		{DialectPostgres, NewDbQueryFilter("a", []interface{}{1, 2}, NOT_IN, ""), "a NOT IN ?", []interface{}{[]interface{}{1, 2}}, nil},
+		{DialectPostgres, NewDbQueryFilter("a", nil, IS_NULL, ""), "a IS NULL", nil, nil},
+		{DialectPostgres, NewDbQueryFilter("a", nil, NOT_NULL, ""), "a IS NOT NULL", nil, nil},
+		{DialectPostgres, NewDbQueryFilter("a", []interface{}{"50%_!"}, LIKE, ""), "a LIKE ? ESCAPE '!'", []interface{}{"%50!%!_!!%"}, nil},
+		{DialectMySQL, NewDbQueryFilter("a", []interface{}{"a_"}, STARTS_WITH, ""), "a LIKE ? ESCAPE '!'", []interface{}{"a!_%"}, nil},
+		{DialectSQLite, NewDbQueryFilter("a", []interface{}{"%z"}, ENDS_WITH, ""), "a LIKE ? ESCAPE '!'", []interface{}{"%!%z"}, nil},
+		{DialectPostgres, NewDbQueryFilter("a", []interface{}{"Bob"}, ILIKE, ""), "a ILIKE ? ESCAPE '!'", []interface{}{"%Bob%"}, nil},
+		{DialectMySQL, NewDbQueryFilter("a", []interface{}{"Bob"}, ILIKE, ""), "LOWER(a) LIKE LOWER(?) ESCAPE '!'", []interface{}{"%Bob%"}, nil},
+		{DialectPostgres, NewDbQueryFilter("a", []interface{}{map[string]interface{}{"k": 1}}, JSON_CONTAINS, ""), "a @> CAST(? AS jsonb)", []interface{}{`{"k":1}`}, nil},
+		{DialectMySQL, NewDbQueryFilter("a", []interface{}{map[string]interface{}{"k": 1}}, JSON_CONTAINS, ""), "JSON_CONTAINS(a, ?)", []interface{}{`{"k":1}`}, nil},
+		{DialectSQLite, NewDbQueryFilter("a", []interface{}{map[string]interface{}{"k": 1}}, JSON_CONTAINS, ""), "", nil, ErrUnsupportedOperation},
+		{DialectPostgres, NewDbQueryFilter("a", []interface{}{"$.k"}, JSON_PATH, ""), "jsonb_path_exists(a, CAST(? AS jsonpath))", []interface{}{"$.k"}, nil},
+		{DialectSQLite, NewDbQueryFilter("a", []interface{}{"$.k"}, JSON_PATH, ""), "json_type(a, ?) IS NOT NULL", []interface{}{"$.k"}, nil},
+		{DialectPostgres, NewDbQueryFilter("a", []interface{}{`$.** ? (@ like_regex "a")`}, JSON_PATH, ""), "", nil, ErrInvalidValues},
+		{DialectMySQL, NewDbQueryFilter("a", []interface{}{"$**.k"}, JSON_PATH, ""), "", nil, ErrInvalidValues},
+		{DialectSQLite, NewDbQueryFilter("a", []interface{}{"$.k[*]"}, JSON_PATH, ""), "", nil, ErrInvalidValues},
+		{DialectSQLite, NewDbQueryFilter("a", []interface{}{1}, JSON_PATH, ""), "", nil, ErrInvalidValues},
+		{DialectPostgres, NewDbQueryFilter("a", []interface{}{"fat cat"}, SEARCH, ""), "a @@ plainto_tsquery(?)", []interface{}{"fat cat"}, nil},
+		{DialectMySQL, NewDbQueryFilter("a", []interface{}{"fat cat"}, SEARCH, ""), "MATCH(a) AGAINST (? IN NATURAL LANGUAGE MODE)", []interface{}{"fat cat"}, nil},
+		{DialectSQLite, NewDbQueryFilter("a", []interface{}{"fat cat"}, SEARCH, ""), "", nil, ErrUnsupportedOperation},
+	}
+	for _, tt := range tests {
+		where, values, err := NewDbQueryExpr(FilterExpr(tt.filter), 10, 1, nil).render(tt.dialect, rawColumn)
+		if !errors.Is(err, tt.err) || where != tt.where || !reflect.DeepEqual(values, tt.values) {
+			t.Errorf("%s %s: got %q %v %v, want %q %v %v", tt.dialect, tt.filter.FilterOperation, where, values, err, tt.where, tt.values, tt.err)
+		}
+	}
+}
+
+type document struct {
+	Id   string `gorm:"primaryKey" json:"id"`
+	Name string `json:"name" query:"LIKE,STARTS_WITH,ENDS_WITH,ILIKE"`
+	Data string `json:"data" query:"JSON_PATH"`
+}
+
+func TestDbQueryOperationsSQLite(t *testing.T) {
+	dbContext, err := gdksqlite.NewSQLiteDbContext(&database.DbConfig{DbType: DialectSQLite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := dbContext.GetMasterDb()
+	if err := db.AutoMigrate(&document{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []document{
+		{Id: "1", Name: "50% off", Data: `{"a":[{"b":1}]}`},
+		{Id: "2", Name: "500 OFF", Data: `{"a":[]}`},
+		{Id: "3", Name: "a_b!c", Data: `{"c":null}`},
+	} {
+		if err := db.Create(&d).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	schema, err := SchemaOf(&document{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		filter DbQueryFilter
+		ids    string
+	}{
+		{NewDbQueryFilter("name", []interface{}{"0%"}, LIKE, ""), "1"},
+		{NewDbQueryFilter("name", []interface{}{"50%"}, STARTS_WITH, ""), "1"},
+		{NewDbQueryFilter("name", []interface{}{"_b!c"}, ENDS_WITH, ""), "3"},
+		{NewDbQueryFilter("name", []interface{}{"_"}, LIKE, ""), "3"},
+		{NewDbQueryFilter("name", []interface{}{"oFf"}, ILIKE, ""), "12"},
+		{NewDbQueryFilter("data", []interface{}{"$.a[0].b"}, JSON_PATH, ""), "1"},
+		{NewDbQueryFilter("data", []interface{}{"$.a"}, JSON_PATH, ""), "12"},
+		{NewDbQueryFilter("data", []interface{}{"$.c"}, JSON_PATH, ""), "3"},
+	}
+	for _, tt := range tests {
+		where, values, order, err := NewDbQueryExpr(FilterExpr(tt.filter), 10, 1, nil).BuildFor(schema, DialectSQLite)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var found []document
+		if err := db.Where(where, values...).Order(order).Find(&found).Error; err != nil {
+			t.Fatalf("%s %v: %v", tt.filter.FilterOperation, tt.filter.FilterValues, err)
+		}
+		ids := ""
+		for _, d := range found {
+			ids += d.Id
+		}
+		if ids != tt.ids {
+			t.Errorf("%s %v matched %q, want %q", tt.filter.FilterOperation, tt.filter.FilterValues, ids, tt.ids)
+		}
+	}
+}
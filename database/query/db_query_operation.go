@@ -0,0 +1,152 @@
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The dialects a DbQuery renders for, named like gorm.Dialector.Name
+const (
+	DialectPostgres = "postgres"
+	DialectMySQL    = "mysql"
+	DialectSQLite   = "sqlite"
+)
+
+// DefaultDialect is the dialect of Build and GetWhereClause
+const DefaultDialect = DialectPostgres
+
+var ErrUnsupportedOperation = errors.New("query: operation not supported by the dialect")
+
+// likeEscape escapes the wildcards of LIKE patterns. It is not a backslash,
+// which MySQL treats as an escape character in string literals.
+const likeEscape = "!"
+
+var likeReplacer = strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_")
+
+// escapeLike makes the wildcards of a user supplied value match literally
+func escapeLike(value interface{}) string {
+	return likeReplacer.Replace(fmt.Sprint(value))
+}
+
+// jsonPathPattern matches the plain paths JSON_PATH accepts, like $.a[0].b,
+// which mean the same in every dialect. Filters, wildcards and recursive
+// descent are rejected.
+var jsonPathPattern = regexp.MustCompile(`^\$(\.[A-Za-z_][A-Za-z0-9_]*|\[[0-9]+\])*$`)
+
+// validJSONPath checks that value is a plain JSON path
+func validJSONPath(value interface{}) bool {
+	path, ok := value.(string)
+	return ok && len(path) <= 256 && jsonPathPattern.MatchString(path)
+}
+
+// valueCount returns the number of values an operation consumes, -1 for at
+// least one
+func valueCount(operation FilterOperation) int {
+	switch operation {
+	case IN, NOT_IN:
+		return -1
+	case BETWEEN:
+		return 2
+	case IS_NULL, NOT_NULL:
+		return 0
+	}
+	return 1
+}
+
+func (r *renderer) renderFilter(filter DbQueryFilter) error {
+	column, vars := r.column(filter.FieldName)
+	r.vars = append(r.vars, vars...)
+	values := filter.FilterValues
+
+	switch filter.FilterOperation {
+	case EQ:
+		r.write(column+" = ?", values[0])
+	case NEQ:
+		r.write(column+" <> ?", values[0])
+	case LT:
+		r.write(column+" < ?", values[0])
+	case LTE:
+		r.write(column+" <= ?", values[0])
+	case GT:
+		r.write(column+" > ?", values[0])
+	case GTE:
+		r.write(column+" >= ?", values[0])
+	case IN:
+		r.write(column+" IN ?", values)
+	case NOT_IN:
+		r.write(column+" NOT IN ?", values)
+	case BETWEEN:
+		r.write(column+" BETWEEN ? AND ?", values[0], values[1])
+	case IS_NULL:
+		r.write(column + " IS NULL")
+	case NOT_NULL:
+		r.write(column + " IS NOT NULL")
+	case LIKE:
+		r.write(column+" LIKE ? ESCAPE '"+likeEscape+"'", "%"+escapeLike(values[0])+"%")
+	case STARTS_WITH:
+		r.write(column+" LIKE ? ESCAPE '"+likeEscape+"'", escapeLike(values[0])+"%")
+	case ENDS_WITH:
+		r.write(column+" LIKE ? ESCAPE '"+likeEscape+"'", "%"+escapeLike(values[0]))
+	case ILIKE:
+		pattern := "%" + escapeLike(values[0]) + "%"
+		if r.dialect == DialectPostgres {
+			r.write(column+" ILIKE ? ESCAPE '"+likeEscape+"'", pattern)
+		} else {
+			r.write("LOWER("+column+") LIKE LOWER(?) ESCAPE '"+likeEscape+"'", pattern)
+		}
+	case JSON_CONTAINS:
+		// 值按 JSON 编码，客户端传入的对象会被解码为 map
+		document, err := json.Marshal(values[0])
+		if err != nil {
+			return &FieldError{Err: ErrInvalidValues, Field: filter.FieldName, Operation: filter.FilterOperation}
+		}
+		switch r.dialect {
+		case DialectPostgres:
+			r.write(column+" @> CAST(? AS jsonb)", string(document))
+		case DialectMySQL:
+			r.write("JSON_CONTAINS("+column+", ?)", string(document))
+		default:
+			return r.unsupported(filter)
+		}
+	case JSON_PATH:
+		if !validJSONPath(values[0]) {
+			return &FieldError{Err: ErrInvalidValues, Field: filter.FieldName, Operation: filter.FilterOperation}
+		}
+		path := values[0].(string)
+		switch r.dialect {
+		case DialectPostgres:
+			r.write("jsonb_path_exists("+column+", CAST(? AS jsonpath))", path)
+		case DialectMySQL:
+			r.write("JSON_CONTAINS_PATH("+column+", 'one', ?)", path)
+		case DialectSQLite:
+			r.write("json_type("+column+", ?) IS NOT NULL", path)
+		default:
+			return r.unsupported(filter)
+		}
+	case SEARCH:
+		// Postgres 对 text 列隐式调用 to_tsvector，tsvector 列直接匹配
+		switch r.dialect {
+		case DialectPostgres:
+			r.write(column+" @@ plainto_tsquery(?)", values[0])
+		case DialectMySQL:
+			r.write("MATCH("+column+") AGAINST (? IN NATURAL LANGUAGE MODE)", values[0])
+		default:
+			return r.unsupported(filter)
+		}
+	default:
+		return &FieldError{Err: ErrOperatorNotAllowed, Field: filter.FieldName, Operation: filter.FilterOperation}
+	}
+	return nil
+}
+
+func (r *renderer) write(sql string, vars ...interface{}) {
+	r.sb.WriteString(sql)
+	r.vars = append(r.vars, vars...)
+}
+
+func (r *renderer) unsupported(filter DbQueryFilter) error {
+	return fmt.Errorf("%w: %s on %s", ErrUnsupportedOperation, filter.FilterOperation, r.dialect)
+}
@@ -0,0 +1,315 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm/schema"
+)
+
+var (
+	ErrUnknownField       = errors.New("query: unknown field")
+	ErrOperatorNotAllowed = errors.New("query: operator not allowed")
+	ErrFieldNotSortable   = errors.New("query: field not sortable")
+	ErrInvalidValues      = errors.New("query: invalid filter values")
+	ErrInvalidConnector   = errors.New("query: invalid connector")
+)
+
+// FieldError reports a field of a DbQuery rejected by a Schema. Err is one of
+// the ErrUnknownField family, so callers can test it with errors.Is.
+type FieldError struct {
+	Field     string
+	Operation FilterOperation
+	Err       error
+}
+
+func (e *FieldError) Error() string {
+	if e.Operation != "" {
+		return fmt.Sprintf("%s: %q with %s", e.Err, e.Field, e.Operation)
+	}
+	return fmt.Sprintf("%s: %q", e.Err, e.Field)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// operations are every FilterOperation a Field can allow
+var operations = []FilterOperation{
+	EQ, NEQ, LT, LTE, GT, GTE, LIKE, IN, BETWEEN,
+	NOT_IN, IS_NULL, NOT_NULL, STARTS_WITH, ENDS_WITH, ILIKE, JSON_CONTAINS, JSON_PATH, SEARCH,
+}
+
+// SEARCH needs a full-text index, so it is only allowed by a query tag
+var (
+	stringOperations  = []FilterOperation{EQ, NEQ, LIKE, ILIKE, STARTS_WITH, ENDS_WITH, IN, NOT_IN, IS_NULL, NOT_NULL}
+	orderedOperations = []FilterOperation{EQ, NEQ, LT, LTE, GT, GTE, IN, NOT_IN, BETWEEN, IS_NULL, NOT_NULL}
+	boolOperations    = []FilterOperation{EQ, NEQ, IS_NULL, NOT_NULL}
+	jsonOperations    = []FilterOperation{JSON_CONTAINS, JSON_PATH, IS_NULL, NOT_NULL}
+	defaultOperations = []FilterOperation{EQ, NEQ, IN, NOT_IN, IS_NULL, NOT_NULL}
+)
+
+// columnPattern matches the column names a Schema accepts, optionally
+// qualified by a table name
+var columnPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Field is a field clients may filter or sort on
+type Field struct {
+	// Name is the public name used in DbQuery.FieldName
+	Name string
+	// Column is the column the name maps to
+	Column string
+	// Operations are the filter operations allowed on the field
+	Operations []FilterOperation
+	// Sortable allows the field in DbQuery.OrderBy
+	Sortable bool
+	// Keyset declares that the column never holds NULL, so it may order
+	// keyset pages even without a not null constraint in the model
+	Keyset bool
+}
+
+// Schema whitelists the fields of a DbQuery. Field names are mapped to
+// columns and every filter is checked against the operations of its field
+// before any SQL is built.
+type Schema struct {
+	fields     map[string]Field
+	primaryKey string
+}
+
+// defaultPrimaryKey is the primary key column of a Schema created with
+// NewSchema
+const defaultPrimaryKey = "id"
+
+// NewSchema creates a Schema from explicitly declared fields
+func NewSchema(fields ...Field) (*Schema, error) {
+	s := &Schema{fields: make(map[string]Field, len(fields)), primaryKey: defaultPrimaryKey}
+	for _, field := range fields {
+		if err := s.Set(field); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// SchemaOf derives a Schema from a gorm model. The public name of a field is
+// its json tag name, or its column when it has none, the column comes from
+// the gorm tags and namer, which must be the NamingStrategy of the *gorm.DB
+// the model is queried with. A nil namer is the default NamingStrategy. The
+// allowed operations follow the data type of the field and are replaced by
+// the operations a query tag lists, where "nosort" disallows sorting,
+// "keyset" sets Field.Keyset and "-" hides the field:
+//
+//	Name   string `json:"name" query:"EQ,LIKE"`
+//	Secret string `json:"secret" query:"-"`
+func SchemaOf(model interface{}, namer schema.Namer) (*Schema, error) {
+	if namer == nil {
+		namer = schema.NamingStrategy{}
+	}
+	parsed, err := schema.Parse(model, &sync.Map{}, namer)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Schema{fields: make(map[string]Field, len(parsed.Fields)), primaryKey: defaultPrimaryKey}
+	if parsed.PrioritizedPrimaryField != nil {
+		s.primaryKey = parsed.PrioritizedPrimaryField.DBName
+	}
+	for _, f := range parsed.Fields {
+		if f.DBName == "" {
+			continue
+		}
+		tag, hasTag := f.Tag.Lookup("query")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.DBName
+		}
+
+		field := Field{
+			Name:       name,
+			Column:     f.DBName,
+			Operations: operationsOf(f.GORMDataType),
+			Sortable:   true,
+		}
+		if hasTag {
+			// 标签中列出操作时才替换默认操作，只有 nosort 等选项时保留默认值
+			var tagOperations []FilterOperation
+			for _, option := range strings.Split(tag, ",") {
+				switch option = strings.TrimSpace(option); option {
+				case "":
+				case "nosort":
+					field.Sortable = false
+				case "keyset":
+					field.Keyset = true
+				default:
+					tagOperations = append(tagOperations, FilterOperation(strings.ToUpper(option)))
+				}
+			}
+			if len(tagOperations) > 0 {
+				field.Operations = tagOperations
+			}
+		}
+		if err := s.Set(field); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func operationsOf(dataType schema.DataType) []FilterOperation {
+	switch dataType {
+	case schema.String:
+		return stringOperations
+	case schema.Int, schema.Uint, schema.Float, schema.Time:
+		return orderedOperations
+	case schema.Bool:
+		return boolOperations
+	case "json", "jsonb":
+		return jsonOperations
+	}
+	return defaultOperations
+}
+
+// Set adds a field, replacing any field with the same name
+func (s *Schema) Set(field Field) error {
+	if field.Name == "" {
+		return errors.New("query: field name is required")
+	}
+	if field.Column == "" {
+		field.Column = field.Name
+	}
+	if !columnPattern.MatchString(field.Column) {
+		return fmt.Errorf("query: invalid column %q of field %q", field.Column, field.Name)
+	}
+	for _, operation := range field.Operations {
+		if !slices.Contains(operations, operation) {
+			return fmt.Errorf("query: unknown operation %q of field %q", operation, field.Name)
+		}
+	}
+	s.fields[field.Name] = field
+	return nil
+}
+
+// SetKeyset sets Field.Keyset on fields whose tags cannot be changed, such as
+// CreatedAt of gorm.Model
+func (s *Schema) SetKeyset(names ...string) error {
+	for _, name := range names {
+		field, found := s.fields[name]
+		if !found {
+			return &FieldError{Err: ErrUnknownField, Field: name}
+		}
+		field.Keyset = true
+		s.fields[name] = field
+	}
+	return nil
+}
+
+// PrimaryKey returns the primary key column, which orders the queries that
+// have no order of their own
+func (s *Schema) PrimaryKey() string {
+	return s.primaryKey
+}
+
+// SetPrimaryKey sets the primary key column, it is "id" for a Schema created
+// with NewSchema and the primary key of the model for one made by SchemaOf
+func (s *Schema) SetPrimaryKey(column string) error {
+	if !columnPattern.MatchString(column) {
+		return fmt.Errorf("query: invalid primary key column %q", column)
+	}
+	s.primaryKey = column
+	return nil
+}
+
+// Remove hides fields from the clients
+func (s *Schema) Remove(names ...string) {
+	for _, name := range names {
+		delete(s.fields, name)
+	}
+}
+
+// Field returns the field of a public name
+func (s *Schema) Field(name string) (Field, bool) {
+	field, found := s.fields[name]
+	return field, found
+}
+
+// Fields returns the public names of the fields in alphabetical order
+func (s *Schema) Fields() []string {
+	names := make([]string, 0, len(s.fields))
+	for name := range s.fields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Validate checks every filter and order of q against the schema, and the
+// shape of its expression tree
+func (s *Schema) Validate(q *DbQuery) error {
+	for _, where := range q.QueryWheres {
+		if !validConnector(where.Connector) {
+			return &FieldError{Err: ErrInvalidConnector, Field: string(where.Connector)}
+		}
+		for _, filter := range where.QueryFilters {
+			if err := s.validateFilter(filter); err != nil {
+				return err
+			}
+		}
+	}
+	if q.Where != nil {
+		if err := q.Where.walk(1, s.validateFilter); err != nil {
+			return err
+		}
+	}
+	for _, order := range q.OrderBy {
+		field, found := s.fields[order.FieldName]
+		if !found {
+			return &FieldError{Err: ErrUnknownField, Field: order.FieldName}
+		}
+		if !field.Sortable {
+			return &FieldError{Err: ErrFieldNotSortable, Field: order.FieldName}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateFilter(filter DbQueryFilter) error {
+	field, found := s.fields[filter.FieldName]
+	if !found {
+		return &FieldError{Err: ErrUnknownField, Field: filter.FieldName}
+	}
+	if !slices.Contains(field.Operations, filter.FilterOperation) {
+		return &FieldError{Err: ErrOperatorNotAllowed, Field: filter.FieldName, Operation: filter.FilterOperation}
+	}
+	if !validConnector(filter.Connector) {
+		return &FieldError{Err: ErrInvalidConnector, Field: filter.FieldName}
+	}
+	if !validValues(filter) {
+		return &FieldError{Err: ErrInvalidValues, Field: filter.FieldName, Operation: filter.FilterOperation}
+	}
+	return nil
+}
+
+func validConnector(connector Connector) bool {
+	return connector == "" || connector == AND || connector == OR
+}
+
+// validValues checks the number of values an operation consumes
+func validValues(filter DbQueryFilter) bool {
+	if filter.FilterOperation == JSON_PATH {
+		return len(filter.FilterValues) == 1 && validJSONPath(filter.FilterValues[0])
+	}
+	if count := valueCount(filter.FilterOperation); count >= 0 {
+		return len(filter.FilterValues) == count
+	}
+	return len(filter.FilterValues) > 0
+}
@@ -0,0 +1,100 @@
+package query
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+type account struct {
+	Id        string    `gorm:"primaryKey" json:"id"`
+	UserName  string    `gorm:"column:login" json:"userName" query:"EQ,LIKE"`
+	Balance   int64     `json:"balance"`
+	Password  string    `json:"-"`
+	Token     string    `json:"token" query:"-"`
+	CreatedAt time.Time `json:"createdAt" query:"GT,LT,nosort"`
+	Score     int       `json:"score" query:"nosort"`
+}
+
+func TestSchemaOf(t *testing.T) {
+	schema, err := SchemaOf(&account{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(schema.Fields(), ","); got != "balance,createdAt,id,score,userName" {
+		t.Fatalf("fields = %s", got)
+	}
+	if field, _ := schema.Field("userName"); field.Column != "login" || len(field.Operations) != 2 {
+		t.Errorf("userName = %+v", field)
+	}
+	if field, _ := schema.Field("balance"); field.Column != "balance" || len(field.Operations) != len(orderedOperations) {
+		t.Errorf("balance = %+v", field)
+	}
+	// 只有 nosort 的标签保留默认操作
+	if field, _ := schema.Field("score"); field.Sortable || len(field.Operations) != len(orderedOperations) {
+		t.Errorf("score = %+v", field)
+	}
+}
+
+func TestDbQueryBuildDefaultOrder(t *testing.T) {
+	// 主键列按命名策略命名时，默认排序使用该列而不是 id
+	s, err := SchemaOf(&account{}, schema.NamingStrategy{NoLowerCase: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, order, err := NewDbQuery(nil, 10, 1, nil).Build(s)
+	if err != nil || len(order.Columns) != 1 || order.Columns[0].Column.Name != "Id" {
+		t.Fatalf("order = %+v, %v", order, err)
+	}
+}
+
+func TestDbQueryBuild(t *testing.T) {
+	schema, err := SchemaOf(&account{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := NewDbQuery([]DbQueryWhere{
+		NewDbQueryWhere([]DbQueryFilter{
+			NewDbQueryFilter("userName", []interface{}{"bob"}, LIKE, ""),
+			NewDbQueryFilter("balance", []interface{}{10, 20}, BETWEEN, ""),
+		}, AND),
+	}, 10, 1, []DbQueryOrderBy{NewDDbQueryOrderBy("balance", false)})
+	where, values, order, err := q.Build(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if where != "(? LIKE ? ESCAPE '!' AND ? BETWEEN ? AND ?)" || len(values) != 5 || values[0] != (clause.Column{Name: "login"}) {
+		t.Errorf("where = %q, values = %v", where, values)
+	}
+	if len(order.Columns) != 1 || order.Columns[0].Column.Name != "balance" || !order.Columns[0].Desc {
+		t.Errorf("order = %+v", order)
+	}
+
+	rejected := []struct {
+		name   string
+		filter DbQueryFilter
+		order  []DbQueryOrderBy
+		want   error
+	}{
+		{"injected field", NewDbQueryFilter("1=1; DROP TABLE account; --", []interface{}{1}, EQ, ""), nil, ErrUnknownField},
+		{"hidden field", NewDbQueryFilter("token", []interface{}{"x"}, EQ, ""), nil, ErrUnknownField},
+		{"column name", NewDbQueryFilter("login", []interface{}{"x"}, EQ, ""), nil, ErrUnknownField},
+		{"operation", NewDbQueryFilter("userName", []interface{}{"a", "b"}, IN, ""), nil, ErrOperatorNotAllowed},
+		{"values", NewDbQueryFilter("balance", []interface{}{1}, BETWEEN, ""), nil, ErrInvalidValues},
+		{"connector", DbQueryFilter{FieldName: "balance", FilterValues: []interface{}{1}, FilterOperation: EQ, Connector: "OR 1=1 OR"}, nil, ErrInvalidConnector},
+		{"order", NewDbQueryFilter("balance", []interface{}{1}, EQ, ""), []DbQueryOrderBy{NewDDbQueryOrderBy("createdAt", true)}, ErrFieldNotSortable},
+	}
+	for _, tt := range rejected {
+		q := NewDbQuery([]DbQueryWhere{NewDbQueryWhere([]DbQueryFilter{tt.filter}, AND)}, 10, 1, tt.order)
+		_, _, _, err := q.Build(schema)
+		var fieldErr *FieldError
+		if !errors.Is(err, tt.want) || !errors.As(err, &fieldErr) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -6,10 +6,20 @@ import (
 	"github.com/loongkirin/gdk/database/query"
 )
 
+// Page is a page of a keyset paginated query. NextCursor and PrevCursor are
+// empty when there is no page after or before it.
+type Page[T any] struct {
+	DataList   []T
+	NextCursor string
+	PrevCursor string
+}
+
 type Repository[T any] interface {
 	Migrate(ctx context.Context, data *T) error
 	QueryById(ctx context.Context, id string) (*T, error)
 	Query(ctx context.Context, query *query.DbQuery) ([]T, error)
+	// QueryPage returns the page of query after or before query.Cursor
+	QueryPage(ctx context.Context, query *query.DbQuery) (*Page[T], error)
 	Add(ctx context.Context, data *T) (*T, error)
 	Update(ctx context.Context, data *T) (*T, error)
 	Delete(ctx context.Context, data *T) (bool, error)
@@ -0,0 +1,130 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Locker defines the interface for a distributed lock
+type Locker interface {
+	// Lock attempts to acquire the lock
+	Lock(ctx context.Context) error
+	// LockWithRetry attempts to acquire the lock with exponential backoff
+	LockWithRetry(ctx context.Context, maxRetries int, initialDelay time.Duration) error
+	// Unlock releases the lock if it is still held
+	Unlock(ctx context.Context) error
+	// Refresh extends the lock duration
+	Refresh(ctx context.Context) error
+	// Close releases any resources held by the lock
+	Close(ctx context.Context) error
+}
+
+// Factory creates a Locker for key whose lease lasts expiration, every
+// Locker it returns has a unique owner value
+type Factory func(key string, expiration time.Duration) (Locker, error)
+
+// Common errors that can be returned by Locker implementations
+var (
+	ErrLockNotObtained   = errors.New("lock not obtained")
+	ErrLockNotHeld       = errors.New("lock not held")
+	ErrInvalidContext    = errors.New("invalid context")
+	ErrLockExpired       = errors.New("lock has expired")
+	ErrAlreadyLocked     = errors.New("lock is already held")
+	ErrLockKeyRequired   = errors.New("lock key is required")
+	ErrLockValueRequired = errors.New("lock value is required")
+)
+
+// ExponentialBackoff implements exponential backoff algorithm
+type ExponentialBackoff struct {
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Factor       float64
+	current      time.Duration
+}
+
+// NextBackoff returns the next backoff delay
+func (b *ExponentialBackoff) NextBackoff() time.Duration {
+	if b.current == 0 {
+		b.current = b.InitialDelay
+		return b.current
+	}
+
+	b.current = time.Duration(float64(b.current) * b.Factor)
+	if b.current > b.MaxDelay {
+		b.current = b.MaxDelay
+	}
+	return b.current
+}
+
+// LockWithRetry calls lock until it succeeds, fails with an error other than
+// ErrLockNotObtained or maxRetries is reached, backing off exponentially.
+// It implements Locker.LockWithRetry on top of Locker.Lock.
+func LockWithRetry(ctx context.Context, maxRetries int, initialDelay time.Duration, lock func(ctx context.Context) error) error {
+	if ctx == nil {
+		return ErrInvalidContext
+	}
+
+	backoff := &ExponentialBackoff{
+		InitialDelay: initialDelay,
+		MaxDelay:     initialDelay * 10,
+		Factor:       2,
+	}
+
+	for i := 0; i <= maxRetries; i++ {
+		err := lock(ctx)
+		if err == nil {
+			return nil
+		}
+		if err != ErrLockNotObtained {
+			return err
+		}
+		if i == maxRetries {
+			return ErrLockNotObtained
+		}
+
+		delay := backoff.NextBackoff()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+			// Continue with next retry
+		}
+	}
+	return ErrLockNotObtained
+}
+
+// AutoRefresh starts a goroutine that calls refresh every refreshInterval
+// until it fails, ctx is done or the returned stop function is called. The
+// returned channel receives the error that stopped the refreshes.
+func AutoRefresh(ctx context.Context, refreshInterval time.Duration, refresh func(ctx context.Context) error) (chan error, func()) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	resultCh := make(chan error, 1)
+	stopCh := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+		defer close(resultCh)
+
+		for {
+			select {
+			case <-ctx.Done():
+				resultCh <- ctx.Err()
+				return
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				if err := refresh(ctx); err != nil {
+					resultCh <- err
+					return
+				}
+			}
+		}
+	}()
+
+	return resultCh, func() { close(stopCh) }
+}
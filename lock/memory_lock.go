@@ -0,0 +1,176 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/loongkirin/gdk/util"
+)
+
+// MemoryLockStore holds the locks shared by MemoryLocks. Locks with the same
+// key in the same store exclude each other, like keys in a Redis instance.
+type MemoryLockStore struct {
+	mu    sync.Mutex
+	locks map[string]memoryLockEntry
+}
+
+type memoryLockEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// NewMemoryLockStore creates an empty MemoryLockStore
+func NewMemoryLockStore() *MemoryLockStore {
+	return &MemoryLockStore{locks: make(map[string]memoryLockEntry)}
+}
+
+// Factory returns a Factory creating MemoryLocks in the store
+func (s *MemoryLockStore) Factory() Factory {
+	return func(key string, expiration time.Duration) (Locker, error) {
+		return NewMemoryLock(s, key, util.GenerateId(), expiration)
+	}
+}
+
+// entry returns the unexpired lock of key, the caller must hold mu
+func (s *MemoryLockStore) entry(key string) (memoryLockEntry, bool) {
+	e, found := s.locks[key]
+	if !found {
+		return e, false
+	}
+	if time.Now().After(e.expiresAt) {
+		delete(s.locks, key)
+		return e, false
+	}
+	return e, true
+}
+
+// MemoryLock is an in-process Locker with the same semantics as the Redis
+// lock: the lock expires after its expiration unless refreshed, and only the
+// holder with the same value can release or refresh it. It is meant for
+// tests and single instance services.
+type MemoryLock struct {
+	store      *MemoryLockStore
+	key        string
+	value      string
+	expiration time.Duration
+	held       int32 // atomic
+}
+
+var _ Locker = (*MemoryLock)(nil)
+
+// NewMemoryLock creates a new lock on key in store
+func NewMemoryLock(store *MemoryLockStore, key, value string, expiration time.Duration) (*MemoryLock, error) {
+	if store == nil {
+		return nil, errors.New("lock store is required")
+	}
+	if key == "" {
+		return nil, ErrLockKeyRequired
+	}
+	if value == "" {
+		return nil, ErrLockValueRequired
+	}
+	if expiration <= 0 {
+		return nil, errors.New("expiration must be positive")
+	}
+
+	return &MemoryLock{
+		store:      store,
+		key:        key,
+		value:      value,
+		expiration: expiration,
+	}, nil
+}
+
+// Lock attempts to acquire the lock
+func (l *MemoryLock) Lock(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+
+	// 租约过期后 held 仍为 1，锁已不属于自己时允许重新获取，与 RedisLock 一致
+	e, found := l.store.entry(l.key)
+	if atomic.LoadInt32(&l.held) == 1 && found && e.value == l.value {
+		return ErrAlreadyLocked
+	}
+	if found {
+		atomic.StoreInt32(&l.held, 0)
+		return ErrLockNotObtained
+	}
+	l.store.locks[l.key] = memoryLockEntry{value: l.value, expiresAt: time.Now().Add(l.expiration)}
+	atomic.StoreInt32(&l.held, 1)
+	return nil
+}
+
+// LockWithRetry attempts to acquire the lock with exponential backoff
+func (l *MemoryLock) LockWithRetry(ctx context.Context, maxRetries int, initialDelay time.Duration) error {
+	return LockWithRetry(ctx, maxRetries, initialDelay, l.Lock)
+}
+
+// Unlock releases the lock if it is still held by this instance
+func (l *MemoryLock) Unlock(ctx context.Context) error {
+	if ctx == nil {
+		return ErrInvalidContext
+	}
+
+	if !atomic.CompareAndSwapInt32(&l.held, 1, 0) {
+		return ErrLockNotHeld
+	}
+
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+
+	if e, found := l.store.entry(l.key); !found || e.value != l.value {
+		return ErrLockNotHeld
+	}
+	delete(l.store.locks, l.key)
+	return nil
+}
+
+// Refresh extends the lock duration if it is still held by this instance
+func (l *MemoryLock) Refresh(ctx context.Context) error {
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+
+	e, found := l.store.entry(l.key)
+	if !found || e.value != l.value {
+		return ErrLockNotHeld
+	}
+	e.expiresAt = time.Now().Add(l.expiration)
+	l.store.locks[l.key] = e
+	return nil
+}
+
+// AutoRefresh starts a goroutine to automatically refresh the lock
+// It returns a channel that will receive refresh errors and a stop function
+func (l *MemoryLock) AutoRefresh(ctx context.Context, refreshInterval time.Duration) (chan error, func()) {
+	if refreshInterval <= 0 {
+		refreshInterval = l.expiration / 3
+	}
+	return AutoRefresh(ctx, refreshInterval, l.Refresh)
+}
+
+// Close releases the lock if it is still held
+func (l *MemoryLock) Close(ctx context.Context) error {
+	if atomic.LoadInt32(&l.held) == 0 {
+		return nil
+	}
+	if err := l.Unlock(ctx); err != nil && err != ErrLockNotHeld {
+		return err
+	}
+	return nil
+}
+
+// String implements fmt.Stringer
+func (l *MemoryLock) String() string {
+	return fmt.Sprintf("MemoryLock{key: %s, expiration: %v}", l.key, l.expiration)
+}
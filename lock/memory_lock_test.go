@@ -0,0 +1,58 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryLock(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryLockStore()
+
+	owner, _ := NewMemoryLock(store, "job", "owner", 50*time.Millisecond)
+	other, _ := NewMemoryLock(store, "job", "other", time.Minute)
+
+	if err := owner.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := owner.Lock(ctx); !errors.Is(err, ErrAlreadyLocked) {
+		t.Errorf("second Lock got %v, want ErrAlreadyLocked", err)
+	}
+	if err := other.Lock(ctx); !errors.Is(err, ErrLockNotObtained) {
+		t.Errorf("other owner got %v, want ErrLockNotObtained", err)
+	}
+	if err := other.Refresh(ctx); !errors.Is(err, ErrLockNotHeld) {
+		t.Errorf("other owner refresh got %v, want ErrLockNotHeld", err)
+	}
+
+	// 过期后其他持有者可以获得锁，原持有者无法再释放
+	time.Sleep(60 * time.Millisecond)
+	if err := other.LockWithRetry(ctx, 3, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := owner.Unlock(ctx); !errors.Is(err, ErrLockNotHeld) {
+		t.Errorf("expired owner unlock got %v, want ErrLockNotHeld", err)
+	}
+	if err := other.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMemoryLockReacquiresAfterExpiry(t *testing.T) {
+	ctx := context.Background()
+	l, _ := NewMemoryLock(NewMemoryLockStore(), "job", "owner", 20*time.Millisecond)
+
+	if err := l.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// 租约过期后未释放，同一实例可以重新获得锁
+	time.Sleep(30 * time.Millisecond)
+	if err := l.Lock(ctx); err != nil {
+		t.Fatalf("Lock after expiry got %v", err)
+	}
+	if err := l.Lock(ctx); !errors.Is(err, ErrAlreadyLocked) {
+		t.Errorf("second Lock got %v, want ErrAlreadyLocked", err)
+	}
+}
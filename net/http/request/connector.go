@@ -6,3 +6,6 @@ const (
 	AND Connector = "AND"
 	OR  Connector = "OR"
 )
+
+// NOT negates the single operand of a QueryExpr
+const NOT Connector = "NOT"
@@ -12,4 +12,19 @@ const (
 	LIKE    Operator = "LIKE"
 	IN      Operator = "IN"
 	BETWEEN Operator = "BETWEEN"
+
+	NOT_IN      Operator = "NOT_IN"
+	IS_NULL     Operator = "IS_NULL"
+	NOT_NULL    Operator = "NOT_NULL"
+	STARTS_WITH Operator = "STARTS_WITH"
+	ENDS_WITH   Operator = "ENDS_WITH"
+	// ILIKE is a case-insensitive LIKE
+	ILIKE Operator = "ILIKE"
+	// JSON_CONTAINS matches JSON documents containing the value
+	JSON_CONTAINS Operator = "JSON_CONTAINS"
+	// JSON_PATH matches JSON documents where the path exists, a plain path of
+	// keys and indexes like $.a[0].b
+	JSON_PATH Operator = "JSON_PATH"
+	// SEARCH is a full-text search
+	SEARCH Operator = "SEARCH"
 )
@@ -2,9 +2,11 @@ package request
 
 type Query struct {
 	QueryWheres []*QueryWhere   `json:"query_wheres"`
+	Where       *QueryExpr      `json:"where,omitempty"`
 	OrderBy     []*QueryOrderBy `json:"order_by"`
 	PageSize    int             `json:"page_size"`
 	PageNumber  int             `json:"page_number"`
+	Cursor      string          `json:"cursor,omitempty"`
 }
 
 func NewQuery(wheres []*QueryWhere, ps int, pn int, order []*QueryOrderBy) *Query {
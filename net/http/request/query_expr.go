@@ -0,0 +1,25 @@
+package request
+
+// QueryExpr is a node of a boolean filter tree, a leaf holds a Filter and an
+// inner node combines its Exprs with AND, OR or NOT
+type QueryExpr struct {
+	Operator Connector    `json:"operator,omitempty"`
+	Exprs    []*QueryExpr `json:"exprs,omitempty"`
+	Filter   *QueryFilter `json:"filter,omitempty"`
+}
+
+func NewFilterExpr(filter *QueryFilter) *QueryExpr {
+	return &QueryExpr{Filter: filter}
+}
+
+func NewAndExpr(exprs ...*QueryExpr) *QueryExpr {
+	return &QueryExpr{Operator: AND, Exprs: exprs}
+}
+
+func NewOrExpr(exprs ...*QueryExpr) *QueryExpr {
+	return &QueryExpr{Operator: OR, Exprs: exprs}
+}
+
+func NewNotExpr(expr *QueryExpr) *QueryExpr {
+	return &QueryExpr{Operator: NOT, Exprs: []*QueryExpr{expr}}
+}
@@ -4,6 +4,10 @@ type Pagination struct {
 	PageSize    int  `json:"page_size"`
 	PageNumber  int  `json:"page_number"`
 	HasNextPage bool `json:"has_next_page"`
+	// NextCursor and PrevCursor select the neighbouring pages of a keyset
+	// paginated list
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
 }
 
 type DataResponse[T any] struct {
@@ -14,3 +18,17 @@ type DataListResponse[T any] struct {
 	DataList   []T        `json:"data_list"`
 	Pagination Pagination `json:"page_info"`
 }
+
+// NewCursorListResponse returns the response of a keyset paginated page,
+// clients pass NextCursor or PrevCursor back to read the neighbouring pages
+func NewCursorListResponse[T any](dataList []T, pageSize int, nextCursor, prevCursor string) DataListResponse[T] {
+	return DataListResponse[T]{
+		DataList: dataList,
+		Pagination: Pagination{
+			PageSize:    pageSize,
+			HasNextPage: nextCursor != "",
+			NextCursor:  nextCursor,
+			PrevCursor:  prevCursor,
+		},
+	}
+}
@@ -0,0 +1,38 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	base := time.Date(2024, 1, 31, 10, 15, 30, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/5 * * * *", time.Date(2024, 1, 31, 10, 20, 0, 0, time.UTC)},
+		{"30 9 * * mon-fri", time.Date(2024, 2, 1, 9, 30, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"15 10 1,15 * 7", time.Date(2024, 2, 1, 10, 15, 0, 0, time.UTC)},
+		{"*/10 * * * * *", time.Date(2024, 1, 31, 10, 15, 40, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 1h", time.Date(2024, 1, 31, 11, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		schedule, err := ParseCron(tt.expr, time.UTC)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		if got := schedule.Next(base); !got.Equal(tt.want) {
+			t.Errorf("%s: Next = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{"", "* * *", "60 * * * *", "5-1 * * * *", "*/0 * * * *", "@every 10ms"} {
+		if _, err := ParseCron(expr, time.UTC); err == nil {
+			t.Errorf("%q: expected an error", expr)
+		}
+	}
+}
@@ -0,0 +1,477 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/loongkirin/gdk/lock"
+	"github.com/loongkirin/gdk/logger"
+	"github.com/loongkirin/gdk/telemetry"
+	"github.com/loongkirin/gdk/util"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	// 定义指标
+	schedulerRunsTotalDef = telemetry.MetricDefinition[float64]{
+		Name:        "scheduler_runs_total",
+		Description: "Total number of scheduled job runs by status",
+		Unit:        "1",
+		Kind:        telemetry.KindCounter,
+	}
+
+	schedulerRunDurationDef = telemetry.MetricDefinition[float64]{
+		Name:        "scheduler_run_duration_seconds",
+		Description: "Scheduled job run duration in seconds",
+		Unit:        "s",
+		Kind:        telemetry.KindHistogram,
+	}
+)
+
+var (
+	ErrJobNameRequired    = errors.New("scheduler: job name is required")
+	ErrJobHandlerRequired = errors.New("scheduler: job handler is required")
+	ErrJobExists          = errors.New("scheduler: job already exists")
+	ErrJobNotFound        = errors.New("scheduler: job not found")
+	// ErrRunningLockLost fails a run whose running lock could not be refreshed
+	ErrRunningLockLost = errors.New("scheduler: running lock lost")
+)
+
+// OverlapPolicy decides what happens when a tick arrives while the previous
+// run of the job is still in progress on any replica
+type OverlapPolicy int
+
+const (
+	// OverlapSkip drops the tick
+	OverlapSkip OverlapPolicy = iota
+	// OverlapQueue runs the tick once the previous run has finished
+	OverlapQueue
+)
+
+// RunStatus is the outcome of a run
+type RunStatus string
+
+const (
+	RunSucceeded RunStatus = "succeeded"
+	RunFailed    RunStatus = "failed"
+	RunTimeout   RunStatus = "timeout"
+	RunSkipped   RunStatus = "skipped"
+)
+
+// Run records one execution of a job
+type Run struct {
+	Job        string
+	Tick       time.Time
+	Instance   string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Status     RunStatus
+	Err        error
+}
+
+// Handler is the function run by a job
+type Handler func(ctx context.Context) error
+
+// Job describes a scheduled job
+type Job struct {
+	// Name identifies the job across replicas
+	Name string
+	// Spec is a cron expression, see ParseCron
+	Spec string
+	// Timeout cancels the context of a run, zero means no timeout
+	Timeout time.Duration
+	// Overlap decides what happens to a tick while the job is still running
+	Overlap OverlapPolicy
+	// Handler is called for every tick
+	Handler Handler
+}
+
+// Options represents configuration options for Scheduler
+type Options struct {
+	// KeyPrefix is prepended to the lock keys
+	KeyPrefix string
+	// LockFactory creates the locks that make every tick run on one replica,
+	// defaults to in-process locks, which only coordinate a single instance
+	LockFactory lock.Factory
+	// TickLockExpiration is how long a claimed tick stays claimed, it must be
+	// longer than the clock skew between the replicas
+	TickLockExpiration time.Duration
+	// RunningLockExpiration is the lease of the lock held while a job runs,
+	// it is refreshed during the run
+	RunningLockExpiration time.Duration
+	// QueuePollInterval is how often a queued tick checks whether the
+	// previous run has finished
+	QueuePollInterval time.Duration
+	// HistorySize is the number of runs kept per job
+	HistorySize int
+	// Location is the time zone of the cron expressions, defaults to time.Local
+	Location *time.Location
+	// Instance identifies this replica in the run history, defaults to a
+	// generated id
+	Instance string
+	// Logger receives a log entry per run, optional
+	Logger logger.Logger
+	// DynamicMeter records run metrics, optional
+	DynamicMeter *telemetry.DynamicMeter[float64]
+}
+
+// DefaultOptions returns the default options for Scheduler
+func DefaultOptions() Options {
+	return Options{
+		KeyPrefix:             "scheduler:",
+		TickLockExpiration:    time.Minute,
+		RunningLockExpiration: 30 * time.Second,
+		QueuePollInterval:     time.Second,
+		HistorySize:           100,
+		Location:              time.Local,
+	}
+}
+
+type entry struct {
+	job      Job
+	schedule Schedule
+	history  []Run
+	next     int
+}
+
+// Scheduler runs jobs on cron schedules. Every replica runs the same jobs,
+// the replica that claims the lock of a tick runs it, so each tick runs once.
+type Scheduler struct {
+	options Options
+	mu      sync.Mutex
+	jobs    map[string]*entry
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewScheduler creates a Scheduler, zero options take their default values
+func NewScheduler(opts Options) (*Scheduler, error) {
+	defaults := DefaultOptions()
+	if opts.KeyPrefix == "" {
+		opts.KeyPrefix = defaults.KeyPrefix
+	}
+	if opts.LockFactory == nil {
+		opts.LockFactory = lock.NewMemoryLockStore().Factory()
+	}
+	if opts.TickLockExpiration <= 0 {
+		opts.TickLockExpiration = defaults.TickLockExpiration
+	}
+	if opts.RunningLockExpiration <= 0 {
+		opts.RunningLockExpiration = defaults.RunningLockExpiration
+	}
+	if opts.QueuePollInterval <= 0 {
+		opts.QueuePollInterval = defaults.QueuePollInterval
+	}
+	if opts.HistorySize <= 0 {
+		opts.HistorySize = defaults.HistorySize
+	}
+	if opts.Location == nil {
+		opts.Location = defaults.Location
+	}
+	if opts.Instance == "" {
+		opts.Instance = util.GenerateId()
+	}
+
+	if opts.DynamicMeter != nil {
+		for _, def := range []telemetry.MetricDefinition[float64]{schedulerRunsTotalDef, schedulerRunDurationDef} {
+			if _, err := opts.DynamicMeter.GetOrCreateMetric(def); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &Scheduler{
+		options: opts,
+		jobs:    make(map[string]*entry),
+	}, nil
+}
+
+// AddJob registers a job, it starts right away when the scheduler is running
+func (s *Scheduler) AddJob(job Job) error {
+	if job.Name == "" {
+		return ErrJobNameRequired
+	}
+	if job.Handler == nil {
+		return ErrJobHandlerRequired
+	}
+	schedule, err := ParseCron(job.Spec, s.options.Location)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, found := s.jobs[job.Name]; found {
+		return ErrJobExists
+	}
+	e := &entry{job: job, schedule: schedule}
+	s.jobs[job.Name] = e
+	if s.ctx != nil {
+		s.startJob(e)
+	}
+	return nil
+}
+
+// Start starts every registered job, runs are cancelled when ctx is done or
+// Stop is called
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ctx != nil {
+		return
+	}
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	for _, e := range s.jobs {
+		s.startJob(e)
+	}
+}
+
+// Stop stops scheduling and waits for the running jobs to return
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.ctx, s.cancel = nil, nil
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	s.wg.Wait()
+}
+
+// History returns the runs of a job on this replica, oldest first
+func (s *Scheduler) History(name string) ([]Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, found := s.jobs[name]
+	if !found {
+		return nil, ErrJobNotFound
+	}
+	runs := make([]Run, 0, len(e.history))
+	if len(e.history) == s.options.HistorySize {
+		runs = append(runs, e.history[e.next:]...)
+		runs = append(runs, e.history[:e.next]...)
+	} else {
+		runs = append(runs, e.history...)
+	}
+	return runs, nil
+}
+
+// startJob starts the loop of a job, the caller must hold mu
+func (s *Scheduler) startJob(e *entry) {
+	ctx := s.ctx
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.loop(ctx, e)
+	}()
+}
+
+func (s *Scheduler) loop(ctx context.Context, e *entry) {
+	for {
+		tick := e.schedule.Next(time.Now())
+		if tick.IsZero() {
+			return
+		}
+
+		timer := time.NewTimer(time.Until(tick))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.runTick(ctx, e, tick)
+		}()
+	}
+}
+
+// runTick claims the tick and runs the job according to its overlap policy
+func (s *Scheduler) runTick(ctx context.Context, e *entry, tick time.Time) {
+	job := e.job
+	log := s.logger(ctx, job.Name, tick)
+
+	// 抢占本次触发，只有一个副本能获得该 tick 的锁，锁不释放直到过期
+	tickLock, err := s.options.LockFactory(s.options.KeyPrefix+job.Name+":tick:"+strconv.FormatInt(tick.Unix(), 10), s.options.TickLockExpiration)
+	if err == nil {
+		err = tickLock.Lock(ctx)
+	}
+	if errors.Is(err, lock.ErrLockNotObtained) {
+		return
+	}
+	if err != nil {
+		if log != nil {
+			log.Error("failed to claim scheduled tick", logger.Fields{"error": err.Error()})
+		}
+		return
+	}
+
+	runningLock, err := s.options.LockFactory(s.options.KeyPrefix+job.Name+":running", s.runningLockExpiration(job))
+	if err == nil {
+		err = s.acquireRunning(ctx, job, runningLock)
+	}
+	if errors.Is(err, lock.ErrLockNotObtained) {
+		s.record(ctx, e, Run{Job: job.Name, Tick: tick, Instance: s.options.Instance, StartedAt: time.Now(), FinishedAt: time.Now(), Status: RunSkipped})
+		return
+	}
+	if err != nil {
+		if log != nil {
+			log.Error("failed to acquire job lock", logger.Fields{"error": err.Error()})
+		}
+		return
+	}
+
+	s.record(ctx, e, s.execute(ctx, job, tick, runningLock))
+}
+
+// acquireRunning takes the running lock of a job, waiting for the previous
+// run to finish when the job queues overlapping ticks
+func (s *Scheduler) acquireRunning(ctx context.Context, job Job, runningLock lock.Locker) error {
+	if job.Overlap != OverlapQueue {
+		return runningLock.Lock(ctx)
+	}
+
+	ticker := time.NewTicker(s.options.QueuePollInterval)
+	defer ticker.Stop()
+	for {
+		err := runningLock.Lock(ctx)
+		if !errors.Is(err, lock.ErrLockNotObtained) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// execute runs the handler while refreshing the running lock
+func (s *Scheduler) execute(ctx context.Context, job Job, tick time.Time, runningLock lock.Locker) (run Run) {
+	run = Run{Job: job.Name, Tick: tick, Instance: s.options.Instance, StartedAt: time.Now()}
+
+	var runCtx context.Context
+	var cancel context.CancelFunc
+	if job.Timeout > 0 {
+		runCtx, cancel = context.WithTimeout(ctx, job.Timeout)
+	} else {
+		runCtx, cancel = context.WithCancel(ctx)
+	}
+	refreshErrs, stop := lock.AutoRefresh(runCtx, s.runningLockExpiration(job)/3, runningLock.Refresh)
+
+	// 续约失败说明锁已丢失，其他副本可能已开始运行该任务，立即取消本次运行
+	lost := make(chan error, 1)
+	watched := make(chan struct{})
+	go func() {
+		defer close(watched)
+		if err, ok := <-refreshErrs; ok && runCtx.Err() == nil {
+			lost <- err
+			cancel()
+		}
+	}()
+
+	defer func() {
+		if r := recover(); r != nil {
+			run.Err = fmt.Errorf("scheduler: job panicked: %v", r)
+		}
+		deadlineExceeded := errors.Is(runCtx.Err(), context.DeadlineExceeded)
+		stop()
+		cancel()
+		<-watched
+		runningLock.Unlock(context.WithoutCancel(ctx))
+
+		select {
+		case err := <-lost:
+			run.Err = fmt.Errorf("%w: %v", ErrRunningLockLost, err)
+		default:
+		}
+
+		run.FinishedAt = time.Now()
+		switch {
+		case run.Err == nil:
+			run.Status = RunSucceeded
+		case deadlineExceeded:
+			run.Status = RunTimeout
+		default:
+			run.Status = RunFailed
+		}
+	}()
+
+	run.Err = job.Handler(runCtx)
+	return run
+}
+
+func (s *Scheduler) runningLockExpiration(job Job) time.Duration {
+	return max(s.options.RunningLockExpiration, job.Timeout)
+}
+
+// record adds a run to the history and reports it
+func (s *Scheduler) record(ctx context.Context, e *entry, run Run) {
+	s.mu.Lock()
+	if len(e.history) < s.options.HistorySize {
+		e.history = append(e.history, run)
+	} else {
+		e.history[e.next] = run
+		e.next = (e.next + 1) % s.options.HistorySize
+	}
+	s.mu.Unlock()
+
+	ctx = context.WithoutCancel(ctx)
+	if log := s.logger(ctx, run.Job, run.Tick); log != nil {
+		fields := logger.Fields{
+			"status":   string(run.Status),
+			"duration": run.FinishedAt.Sub(run.StartedAt).String(),
+		}
+		switch run.Status {
+		case RunSucceeded:
+			log.Info("scheduled job finished", fields)
+		case RunSkipped:
+			log.Warn("scheduled job skipped, previous run still in progress", fields)
+		default:
+			fields["error"] = run.Err.Error()
+			log.Error("scheduled job failed", fields)
+		}
+	}
+
+	if s.options.DynamicMeter != nil {
+		attrs := []attribute.KeyValue{
+			attribute.String("job", run.Job),
+			attribute.String("status", string(run.Status)),
+		}
+		s.options.DynamicMeter.RecordMetric(ctx, telemetry.MetricValue[float64]{
+			Name:       schedulerRunsTotalDef.Name,
+			Value:      1,
+			Attributes: attrs,
+		})
+		if run.Status != RunSkipped {
+			s.options.DynamicMeter.RecordMetric(ctx, telemetry.MetricValue[float64]{
+				Name:       schedulerRunDurationDef.Name,
+				Value:      run.FinishedAt.Sub(run.StartedAt).Seconds(),
+				Attributes: attrs,
+			})
+		}
+	}
+}
+
+func (s *Scheduler) logger(ctx context.Context, job string, tick time.Time) logger.Logger {
+	if s.options.Logger == nil {
+		return nil
+	}
+	return s.options.Logger.WithContext(ctx).WithFields(logger.Fields{
+		"job":      job,
+		"tick":     tick.Format(time.RFC3339),
+		"instance": s.options.Instance,
+	})
+}
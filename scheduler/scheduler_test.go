@@ -0,0 +1,144 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	cacheredis "github.com/loongkirin/gdk/cache/redis"
+	"github.com/loongkirin/gdk/lock"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestSchedulerRunsEachTickOnce(t *testing.T) {
+	store := lock.NewMemoryLockStore()
+	var runs int32
+	job := Job{
+		Name: "report",
+		Spec: "* * * * * *",
+		Handler: func(ctx context.Context) error {
+			atomic.AddInt32(&runs, 1)
+			return nil
+		},
+	}
+
+	// 两个副本共享同一个锁存储
+	var replicas []*Scheduler
+	for i := 0; i < 2; i++ {
+		s, err := NewScheduler(Options{LockFactory: store.Factory()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.AddJob(job); err != nil {
+			t.Fatal(err)
+		}
+		s.Start(context.Background())
+		replicas = append(replicas, s)
+	}
+	time.Sleep(2500 * time.Millisecond)
+
+	ticks := map[time.Time]int{}
+	for _, s := range replicas {
+		s.Stop()
+		history, _ := s.History("report")
+		for _, run := range history {
+			if run.Status != RunSucceeded {
+				t.Errorf("unexpected run %+v", run)
+			}
+			ticks[run.Tick]++
+		}
+	}
+	if len(ticks) < 2 || int(runs) != len(ticks) {
+		t.Fatalf("%d runs for %d ticks", runs, len(ticks))
+	}
+	for tick, n := range ticks {
+		if n != 1 {
+			t.Errorf("tick %v ran %d times", tick, n)
+		}
+	}
+}
+
+func TestSchedulerOverlapAndTimeout(t *testing.T) {
+	s, err := NewScheduler(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AddJob(Job{
+		Name:    "slow",
+		Spec:    "* * * * * *",
+		Timeout: 1500 * time.Millisecond,
+		Overlap: OverlapSkip,
+		Handler: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+	s.Start(context.Background())
+	time.Sleep(2500 * time.Millisecond)
+	s.Stop()
+
+	history, _ := s.History("slow")
+	statuses := map[RunStatus]int{}
+	for _, run := range history {
+		statuses[run.Status]++
+	}
+	if statuses[RunSkipped] == 0 || statuses[RunTimeout] == 0 {
+		t.Errorf("expected skipped and timed out runs, got %v", statuses)
+	}
+}
+
+func TestSchedulerCancelsRunWhenRunningLockIsLost(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	s, err := NewScheduler(Options{
+		LockFactory:           cacheredis.NewLockerFactory(rdb),
+		RunningLockExpiration: 300 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{}, 1)
+	cancelled := make(chan struct{}, 1)
+	s.AddJob(Job{
+		Name: "sync",
+		Spec: "* * * * * *",
+		Handler: func(ctx context.Context) error {
+			select {
+			case started <- struct{}{}:
+			default:
+			}
+			<-ctx.Done()
+			select {
+			case cancelled <- struct{}{}:
+			default:
+			}
+			return nil
+		},
+	})
+	s.Start(context.Background())
+	defer s.Stop()
+
+	select {
+	case <-started:
+	case <-time.After(3 * time.Second):
+		t.Fatal("job did not start")
+	}
+	// 模拟锁丢失（如 Redis 故障切换），运行中的任务必须被取消
+	mr.Del("scheduler:sync:running")
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("handler context was not cancelled after the running lock was lost")
+	}
+	s.Stop()
+
+	history, _ := s.History("sync")
+	if len(history) == 0 || history[0].Status != RunFailed || !errors.Is(history[0].Err, ErrRunningLockLost) {
+		t.Fatalf("unexpected history %+v", history)
+	}
+}
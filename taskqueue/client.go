@@ -0,0 +1,173 @@
+package taskqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/loongkirin/gdk/telemetry"
+	"github.com/loongkirin/gdk/util"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "taskqueue"
+
+// Client enqueues tasks and inspects the queues
+type Client struct {
+	client    redis.UniversalClient
+	keyPrefix string
+}
+
+// NewClient creates a Client on client, usually RedisClient.GetMasterDb().
+// keyPrefix defaults to DefaultKeyPrefix and must match the one of the servers.
+func NewClient(client redis.UniversalClient, keyPrefix string) (*Client, error) {
+	if client == nil {
+		return nil, errors.New("redis client is required")
+	}
+	if keyPrefix == "" {
+		keyPrefix = DefaultKeyPrefix
+	}
+	return &Client{client: client, keyPrefix: keyPrefix}, nil
+}
+
+// Enqueue adds a task and returns its id. The trace context of ctx is stored
+// with the task and restored in the context of its handler. ErrTaskExists is
+// returned while a task with the same id is pending, scheduled, active or dead.
+func (c *Client) Enqueue(ctx context.Context, taskType string, payload []byte, opts EnqueueOptions) (string, error) {
+	if taskType == "" {
+		return "", ErrTaskTypeRequired
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if opts.ID == "" {
+		opts.ID = util.GenerateId()
+	}
+	if opts.Queue == "" {
+		opts.Queue = DefaultQueue
+	}
+	if opts.MaxRetry == 0 {
+		opts.MaxRetry = DefaultMaxRetry
+	}
+	if opts.MaxRetry < 0 {
+		opts.MaxRetry = 0
+	}
+	var processAt int64
+	switch {
+	case !opts.ProcessAt.IsZero():
+		processAt = opts.ProcessAt.UnixMilli()
+	case opts.ProcessIn > 0:
+		processAt = time.Now().Add(opts.ProcessIn).UnixMilli()
+	}
+
+	ctx, span := telemetry.GetTracer(tracerName).Start(ctx, "taskqueue.enqueue "+taskType,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("taskqueue.queue", opts.Queue),
+			attribute.String("taskqueue.task_id", opts.ID),
+		))
+	defer span.End()
+
+	// 将追踪上下文写入任务，处理时恢复
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	headers, err := json.Marshal(carrier)
+	if err != nil {
+		return "", err
+	}
+
+	keys := newQueueKeys(c.keyPrefix, opts.Queue)
+	created, err := c.client.Eval(ctx, enqueueScript,
+		[]string{keys.task(opts.ID), keys.pending(), keys.scheduled()},
+		opts.ID, taskType, payload, opts.MaxRetry, opts.Timeout.Milliseconds(), string(headers), processAt,
+	).Int64()
+	if err != nil {
+		span.RecordError(err)
+		return "", fmt.Errorf("taskqueue: failed to enqueue task: %w", err)
+	}
+	if created == 0 {
+		return "", ErrTaskExists
+	}
+	return opts.ID, nil
+}
+
+// Stats returns the number of tasks in each state of queue
+func (c *Client) Stats(ctx context.Context, queue string) (QueueStats, error) {
+	keys := newQueueKeys(c.keyPrefix, c.queue(queue))
+	pipe := c.client.Pipeline()
+	pending := pipe.LLen(ctx, keys.pending())
+	scheduled := pipe.ZCard(ctx, keys.scheduled())
+	active := pipe.ZCard(ctx, keys.active())
+	dead := pipe.ZCard(ctx, keys.dead())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return QueueStats{}, err
+	}
+	return QueueStats{
+		Pending:   pending.Val(),
+		Scheduled: scheduled.Val(),
+		Active:    active.Val(),
+		Dead:      dead.Val(),
+	}, nil
+}
+
+// DeadTasks returns up to limit tasks of the dead set of queue, most recent first
+func (c *Client) DeadTasks(ctx context.Context, queue string, limit int64) ([]*Task, error) {
+	queue = c.queue(queue)
+	keys := newQueueKeys(c.keyPrefix, queue)
+	if limit <= 0 {
+		limit = 100
+	}
+	ids, err := c.client.ZRevRange(ctx, keys.dead(), 0, limit-1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]*Task, 0, len(ids))
+	for _, id := range ids {
+		task, err := c.GetTask(ctx, queue, id)
+		if errors.Is(err, ErrTaskNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// GetTask returns a task that has not succeeded yet
+func (c *Client) GetTask(ctx context.Context, queue, id string) (*Task, error) {
+	queue = c.queue(queue)
+	fields, err := c.client.HGetAll(ctx, newQueueKeys(c.keyPrefix, queue).task(id)).Result()
+	if err != nil {
+		return nil, err
+	}
+	return parseTask(queue, fields)
+}
+
+// RequeueDead moves a dead task back to the pending list with its retries reset
+func (c *Client) RequeueDead(ctx context.Context, queue, id string) error {
+	keys := newQueueKeys(c.keyPrefix, c.queue(queue))
+	moved, err := c.client.Eval(ctx, requeueDeadScript, []string{keys.dead(), keys.pending(), keys.task(id)}, id).Int64()
+	if err != nil {
+		return err
+	}
+	if moved == 0 {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+func (c *Client) queue(queue string) string {
+	if queue == "" {
+		return DefaultQueue
+	}
+	return queue
+}
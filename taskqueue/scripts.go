@@ -0,0 +1,151 @@
+package taskqueue
+
+// Lua scripts of the queues. A task is a hash, its id moves between the
+// pending list, the scheduled, active and dead sorted sets.
+const (
+	// 获取 Redis 服务器当前时间（毫秒），避免依赖客户端时钟
+	nowScript = `
+		local function now()
+			local t = redis.call("time")
+			return tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+		end`
+
+	// 入队脚本：任务已存在时拒绝（去重），到期任务进入待处理列表，否则进入延时集合
+	// KEYS[1] 任务, KEYS[2] 待处理列表, KEYS[3] 延时集合
+	// ARGV[1] id, ARGV[2] 类型, ARGV[3] 负载, ARGV[4] 最大重试次数, ARGV[5] 超时毫秒数, ARGV[6] 追踪头, ARGV[7] 执行时间毫秒
+	enqueueScript = nowScript + `
+		if redis.call("exists", KEYS[1]) == 1 then
+			return 0
+		end
+		local t = now()
+		redis.call("hset", KEYS[1], "id", ARGV[1], "type", ARGV[2], "payload", ARGV[3], "retried", 0,
+			"max_retry", ARGV[4], "timeout", ARGV[5], "headers", ARGV[6], "enqueued_at", t)
+		local at = tonumber(ARGV[7])
+		if at > t then
+			redis.call("zadd", KEYS[3], at, ARGV[1])
+		else
+			redis.call("lpush", KEYS[2], ARGV[1])
+		end
+		return 1`
+
+	// 出队脚本：先把到期的延时任务和租约过期的执行中任务移回待处理列表，再取出一个任务并加租约
+	// 租约过期（如 worker 崩溃）计为一次重试，超过最大重试次数时放入死信集合
+	// KEYS[1] 待处理列表, KEYS[2] 延时集合, KEYS[3] 执行中集合, KEYS[4] 死信集合
+	// ARGV[1] 任务 key 前缀, ARGV[2] 租约毫秒数, ARGV[3] 租约持有者, ARGV[4] 死信上限
+	dequeueScript = nowScript + `
+		local t = now()
+		for _, id in ipairs(redis.call("zrangebyscore", KEYS[2], "-inf", t, "LIMIT", 0, 100)) do
+			redis.call("zrem", KEYS[2], id)
+			redis.call("lpush", KEYS[1], id)
+		end
+		local dead = false
+		for _, id in ipairs(redis.call("zrangebyscore", KEYS[3], "-inf", t, "LIMIT", 0, 100)) do
+			redis.call("zrem", KEYS[3], id)
+			local key = ARGV[1] .. id
+			local fields = redis.call("hmget", key, "retried", "max_retry")
+			if fields[1] then
+				if tonumber(fields[1]) >= tonumber(fields[2]) then
+					redis.call("hset", key, "error", "lease expired", "worker", "")
+					redis.call("zadd", KEYS[4], t, id)
+					dead = true
+				else
+					redis.call("hincrby", key, "retried", 1)
+					redis.call("hset", key, "error", "lease expired", "worker", "")
+					redis.call("rpush", KEYS[1], id)
+				end
+			end
+		end
+		if dead then
+			local excess = redis.call("zcard", KEYS[4]) - tonumber(ARGV[4])
+			if excess > 0 then
+				for _, id in ipairs(redis.call("zrange", KEYS[4], 0, excess - 1)) do
+					redis.call("del", ARGV[1] .. id)
+				end
+				redis.call("zremrangebyrank", KEYS[4], 0, excess - 1)
+			end
+		end
+		local id = redis.call("rpop", KEYS[1])
+		if not id then
+			return false
+		end
+		local key = ARGV[1] .. id
+		if redis.call("exists", key) == 0 then
+			return false
+		end
+		redis.call("zadd", KEYS[3], t + tonumber(ARGV[2]), id)
+		redis.call("hset", key, "worker", ARGV[3])
+		return redis.call("hgetall", key)`
+
+	// 续租脚本：仍由该持有者执行时延长租约
+	// KEYS[1] 执行中集合, KEYS[2] 任务; ARGV[1] id, ARGV[2] 租约持有者, ARGV[3] 租约毫秒数
+	extendLeaseScript = nowScript + `
+		if redis.call("hget", KEYS[2], "worker") ~= ARGV[2] then
+			return 0
+		end
+		redis.call("zadd", KEYS[1], now() + tonumber(ARGV[3]), ARGV[1])
+		return 1`
+
+	// 完成脚本：删除任务
+	// KEYS[1] 执行中集合, KEYS[2] 任务; ARGV[1] id, ARGV[2] 租约持有者
+	doneScript = `
+		if redis.call("hget", KEYS[2], "worker") ~= ARGV[2] then
+			return 0
+		end
+		redis.call("zrem", KEYS[1], ARGV[1])
+		redis.call("del", KEYS[2])
+		return 1`
+
+	// 重试脚本：增加重试次数并放入延时集合
+	// KEYS[1] 执行中集合, KEYS[2] 延时集合, KEYS[3] 任务
+	// ARGV[1] id, ARGV[2] 租约持有者, ARGV[3] 延迟毫秒数, ARGV[4] 错误信息
+	retryScript = nowScript + `
+		if redis.call("hget", KEYS[3], "worker") ~= ARGV[2] then
+			return 0
+		end
+		redis.call("zrem", KEYS[1], ARGV[1])
+		redis.call("hincrby", KEYS[3], "retried", 1)
+		redis.call("hset", KEYS[3], "error", ARGV[4], "worker", "")
+		redis.call("zadd", KEYS[2], now() + tonumber(ARGV[3]), ARGV[1])
+		return 1`
+
+	// 放回脚本：服务停止时中断的任务放回待处理列表，不计重试次数
+	// KEYS[1] 执行中集合, KEYS[2] 待处理列表, KEYS[3] 任务
+	// ARGV[1] id, ARGV[2] 租约持有者
+	requeueScript = `
+		if redis.call("hget", KEYS[3], "worker") ~= ARGV[2] then
+			return 0
+		end
+		redis.call("zrem", KEYS[1], ARGV[1])
+		redis.call("hset", KEYS[3], "worker", "")
+		redis.call("rpush", KEYS[2], ARGV[1])
+		return 1`
+
+	// 死信脚本：放入死信集合，超过上限时删除最早的死信任务
+	// KEYS[1] 执行中集合, KEYS[2] 死信集合, KEYS[3] 任务
+	// ARGV[1] id, ARGV[2] 租约持有者, ARGV[3] 错误信息, ARGV[4] 死信上限, ARGV[5] 任务 key 前缀
+	killScript = nowScript + `
+		if redis.call("hget", KEYS[3], "worker") ~= ARGV[2] then
+			return 0
+		end
+		redis.call("zrem", KEYS[1], ARGV[1])
+		redis.call("hset", KEYS[3], "error", ARGV[3], "worker", "")
+		redis.call("zadd", KEYS[2], now(), ARGV[1])
+		local excess = redis.call("zcard", KEYS[2]) - tonumber(ARGV[4])
+		if excess > 0 then
+			for _, id in ipairs(redis.call("zrange", KEYS[2], 0, excess - 1)) do
+				redis.call("del", ARGV[5] .. id)
+			end
+			redis.call("zremrangebyrank", KEYS[2], 0, excess - 1)
+		end
+		return 1`
+
+	// 死信重新入队脚本：重置重试次数后放回待处理列表
+	// KEYS[1] 死信集合, KEYS[2] 待处理列表, KEYS[3] 任务; ARGV[1] id
+	requeueDeadScript = `
+		if redis.call("zrem", KEYS[1], ARGV[1]) == 0 then
+			return 0
+		end
+		redis.call("hset", KEYS[3], "retried", 0)
+		redis.call("lpush", KEYS[2], ARGV[1])
+		return 1`
+)
@@ -0,0 +1,414 @@
+package taskqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/loongkirin/gdk/logger"
+	"github.com/loongkirin/gdk/telemetry"
+	"github.com/loongkirin/gdk/util"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// 定义指标
+	taskqueueProcessedTotalDef = telemetry.MetricDefinition[float64]{
+		Name:        "taskqueue_tasks_processed_total",
+		Description: "Total number of processed task attempts by status",
+		Unit:        "1",
+		Kind:        telemetry.KindCounter,
+	}
+
+	taskqueueDurationDef = telemetry.MetricDefinition[float64]{
+		Name:        "taskqueue_task_duration_seconds",
+		Description: "Task attempt duration in seconds",
+		Unit:        "s",
+		Kind:        telemetry.KindHistogram,
+	}
+)
+
+// Status of a task attempt reported in logs and metrics
+const (
+	statusSucceeded = "succeeded"
+	statusRetried   = "retried"
+	statusDead      = "dead"
+	statusRequeued  = "requeued"
+)
+
+// ServerOptions represents configuration options for Server
+type ServerOptions struct {
+	// KeyPrefix must match the one of the clients
+	KeyPrefix string
+	// Queue is the queue the server takes tasks from
+	Queue string
+	// Concurrency is the number of tasks processed at the same time
+	Concurrency int
+	// PollInterval is how long an idle worker waits before polling again
+	PollInterval time.Duration
+	// LeaseDuration is how long a task stays taken without a heartbeat, a
+	// task whose worker dies is processed again once its lease expires, which
+	// counts as a retry
+	LeaseDuration time.Duration
+	// InitialBackoff is the delay before the first retry, it doubles with
+	// every retry up to MaxBackoff
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between retries
+	MaxBackoff time.Duration
+	// DeadMaxSize is the number of tasks kept in the dead set
+	DeadMaxSize int64
+	// Logger receives a log entry per failed attempt, optional
+	Logger logger.Logger
+	// DynamicMeter records task metrics, optional
+	DynamicMeter *telemetry.DynamicMeter[float64]
+}
+
+// DefaultServerOptions returns the default options for Server
+func DefaultServerOptions() ServerOptions {
+	return ServerOptions{
+		KeyPrefix:      DefaultKeyPrefix,
+		Queue:          DefaultQueue,
+		Concurrency:    10,
+		PollInterval:   time.Second,
+		LeaseDuration:  30 * time.Second,
+		InitialBackoff: time.Second,
+		MaxBackoff:     10 * time.Minute,
+		DeadMaxSize:    10000,
+	}
+}
+
+// Server processes the tasks of a queue with a pool of workers. Tasks are
+// delivered at least once: a task whose worker dies before acknowledging it
+// runs again after its lease expires, so handlers should be idempotent.
+type Server struct {
+	client   redis.UniversalClient
+	options  ServerOptions
+	keys     queueKeys
+	mu       sync.RWMutex
+	handlers map[string]Handler
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewServer creates a Server on client, zero options take their default values
+func NewServer(client redis.UniversalClient, opts ServerOptions) (*Server, error) {
+	if client == nil {
+		return nil, errors.New("redis client is required")
+	}
+
+	defaults := DefaultServerOptions()
+	if opts.KeyPrefix == "" {
+		opts.KeyPrefix = defaults.KeyPrefix
+	}
+	if opts.Queue == "" {
+		opts.Queue = defaults.Queue
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaults.Concurrency
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaults.PollInterval
+	}
+	if opts.LeaseDuration <= 0 {
+		opts.LeaseDuration = defaults.LeaseDuration
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = defaults.InitialBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaults.MaxBackoff
+	}
+	if opts.DeadMaxSize <= 0 {
+		opts.DeadMaxSize = defaults.DeadMaxSize
+	}
+
+	if opts.DynamicMeter != nil {
+		for _, def := range []telemetry.MetricDefinition[float64]{taskqueueProcessedTotalDef, taskqueueDurationDef} {
+			if _, err := opts.DynamicMeter.GetOrCreateMetric(def); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &Server{
+		client:   client,
+		options:  opts,
+		keys:     newQueueKeys(opts.KeyPrefix, opts.Queue),
+		handlers: make(map[string]Handler),
+	}, nil
+}
+
+// Handle registers the handler of a task type. Tasks without a handler go to
+// the dead set.
+func (s *Server) Handle(taskType string, handler Handler) error {
+	if taskType == "" {
+		return ErrTaskTypeRequired
+	}
+	if handler == nil {
+		return ErrTaskHandlerRequired
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[taskType] = handler
+	return nil
+}
+
+// Start starts the workers, they stop when ctx is done or Stop is called
+func (s *Server) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		return
+	}
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.loop(ctx)
+	}()
+}
+
+// Stop stops taking tasks and waits for the running ones to return. Running
+// tasks see their context cancelled, those that fail go back to the pending
+// list without using up an attempt.
+func (s *Server) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.cancel = nil
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	s.wg.Wait()
+}
+
+// loop takes tasks as long as a worker slot is free
+func (s *Server) loop(ctx context.Context) {
+	slots := make(chan struct{}, s.options.Concurrency)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case slots <- struct{}{}:
+		}
+
+		task, worker, err := s.dequeue(ctx)
+		if task == nil {
+			<-slots
+			if err != nil && ctx.Err() == nil && s.options.Logger != nil {
+				s.options.Logger.WithContext(ctx).Error("failed to dequeue task", logger.Fields{"queue": s.options.Queue, "error": err.Error()})
+			}
+			timer := time.NewTimer(s.options.PollInterval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			continue
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer func() { <-slots }()
+			s.process(ctx, task, worker)
+		}()
+	}
+}
+
+// dequeue takes the next due task, it returns a nil task when there is none
+func (s *Server) dequeue(ctx context.Context) (*Task, string, error) {
+	worker := util.GenerateId()
+	reply, err := s.client.Eval(ctx, dequeueScript,
+		[]string{s.keys.pending(), s.keys.scheduled(), s.keys.active(), s.keys.dead()},
+		s.keys.taskPrefix(), s.options.LeaseDuration.Milliseconds(), worker, s.options.DeadMaxSize,
+	).Slice()
+	if err == redis.Nil {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	task, err := parseTask(s.options.Queue, pairsToMap(reply))
+	if err != nil {
+		return nil, "", err
+	}
+	return task, worker, nil
+}
+
+// process runs the handler of a task while keeping its lease, then
+// acknowledges, retries or kills the task
+func (s *Server) process(ctx context.Context, task *Task, worker string) {
+	// 恢复入队时的追踪上下文
+	carrier := propagation.MapCarrier{}
+	if task.headers != "" {
+		json.Unmarshal([]byte(task.headers), &carrier)
+	}
+	taskCtx := otel.GetTextMapPropagator().Extract(ctx, carrier)
+	taskCtx, span := telemetry.GetTracer(tracerName).Start(taskCtx, "taskqueue.process "+task.Type,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("taskqueue.queue", task.Queue),
+			attribute.String("taskqueue.task_id", task.ID),
+			attribute.Int("taskqueue.retried", task.Retried),
+		))
+	defer span.End()
+
+	start := time.Now()
+	err := s.run(taskCtx, task, worker)
+
+	// 任务结果在 ctx 取消后也要写回
+	ackCtx := context.WithoutCancel(taskCtx)
+	var status string
+	switch {
+	case err == nil:
+		status = statusSucceeded
+		err = s.ack(ackCtx, doneScript, []string{s.keys.active(), s.keys.task(task.ID)}, task.ID, worker)
+	case ctx.Err() != nil && !errors.Is(err, ErrSkipRetry):
+		// 服务停止导致的失败不是任务本身的问题，放回队列且不消耗重试次数
+		status = statusRequeued
+		err = s.ack(ackCtx, requeueScript, []string{s.keys.active(), s.keys.pending(), s.keys.task(task.ID)}, task.ID, worker)
+	case errors.Is(err, ErrSkipRetry) || task.Retried >= task.MaxRetry:
+		status = statusDead
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.log(ackCtx, task, status, err)
+		err = s.ack(ackCtx, killScript, []string{s.keys.active(), s.keys.dead(), s.keys.task(task.ID)},
+			task.ID, worker, err.Error(), s.options.DeadMaxSize, s.keys.taskPrefix())
+	default:
+		status = statusRetried
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.log(ackCtx, task, status, err)
+		err = s.ack(ackCtx, retryScript, []string{s.keys.active(), s.keys.scheduled(), s.keys.task(task.ID)},
+			task.ID, worker, s.backoff(task.Retried).Milliseconds(), err.Error())
+	}
+	if err != nil && s.options.Logger != nil {
+		s.options.Logger.WithContext(ackCtx).Error("failed to acknowledge task", logger.Fields{"queue": task.Queue, "task_id": task.ID, "error": err.Error()})
+	}
+	s.record(ackCtx, task, status, time.Since(start))
+}
+
+// run calls the handler, keeping the lease of the task until it returns
+func (s *Server) run(ctx context.Context, task *Task, worker string) (err error) {
+	s.mu.RLock()
+	handler, found := s.handlers[task.Type]
+	s.mu.RUnlock()
+	if !found {
+		return fmt.Errorf("no handler for task type %s: %w", task.Type, ErrSkipRetry)
+	}
+
+	var runCtx context.Context
+	var cancel context.CancelFunc
+	if task.Timeout > 0 {
+		runCtx, cancel = context.WithTimeout(ctx, task.Timeout)
+	} else {
+		runCtx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	heartbeatDone := make(chan struct{})
+	defer close(heartbeatDone)
+	go s.heartbeat(runCtx, cancel, task, worker, heartbeatDone)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("taskqueue: task panicked: %v", r)
+		}
+	}()
+	return handler(runCtx, task)
+}
+
+// heartbeat extends the lease of a task, cancelling the handler when the
+// lease is lost to another worker
+func (s *Server) heartbeat(ctx context.Context, cancel context.CancelFunc, task *Task, worker string, done chan struct{}) {
+	ticker := time.NewTicker(s.options.LeaseDuration / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		extended, err := s.client.Eval(context.WithoutCancel(ctx), extendLeaseScript,
+			[]string{s.keys.active(), s.keys.task(task.ID)},
+			task.ID, worker, s.options.LeaseDuration.Milliseconds(),
+		).Int64()
+		if err == nil && extended == 0 {
+			cancel()
+			return
+		}
+	}
+}
+
+func (s *Server) ack(ctx context.Context, script string, keys []string, args ...interface{}) error {
+	result, err := s.client.Eval(ctx, script, keys, args...).Int64()
+	if err != nil {
+		return err
+	}
+	if result == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// backoff returns the delay before the next attempt of a task retried times
+func (s *Server) backoff(retried int) time.Duration {
+	delay := s.options.InitialBackoff
+	for i := 0; i < retried && delay < s.options.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, s.options.MaxBackoff)
+}
+
+func (s *Server) log(ctx context.Context, task *Task, status string, err error) {
+	if s.options.Logger == nil {
+		return
+	}
+	fields := logger.Fields{
+		"queue":     task.Queue,
+		"task_id":   task.ID,
+		"task_type": task.Type,
+		"retried":   task.Retried,
+		"status":    status,
+		"error":     err.Error(),
+	}
+	if status == statusDead {
+		s.options.Logger.WithContext(ctx).Error("task moved to dead set", fields)
+		return
+	}
+	s.options.Logger.WithContext(ctx).Warn("task failed, will retry", fields)
+}
+
+func (s *Server) record(ctx context.Context, task *Task, status string, duration time.Duration) {
+	if s.options.DynamicMeter == nil {
+		return
+	}
+	attributes := []attribute.KeyValue{
+		attribute.String("queue", task.Queue),
+		attribute.String("task_type", task.Type),
+		attribute.String("status", status),
+	}
+	s.options.DynamicMeter.RecordMetric(ctx, telemetry.MetricValue[float64]{
+		Name:       taskqueueProcessedTotalDef.Name,
+		Value:      1,
+		Attributes: attributes,
+	})
+	s.options.DynamicMeter.RecordMetric(ctx, telemetry.MetricValue[float64]{
+		Name:       taskqueueDurationDef.Name,
+		Value:      duration.Seconds(),
+		Attributes: attributes,
+	})
+}
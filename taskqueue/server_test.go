@@ -0,0 +1,219 @@
+package taskqueue
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestQueue(t *testing.T, opts ServerOptions) (*Client, *Server) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	client, err := NewClient(rdb, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.PollInterval = 10 * time.Millisecond
+	opts.InitialBackoff = 10 * time.Millisecond
+	server, err := NewServer(rdb, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, server
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEnqueueDeduplicatesById(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newTestQueue(t, ServerOptions{})
+
+	if _, err := client.Enqueue(ctx, "email", []byte("a"), EnqueueOptions{ID: "order-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Enqueue(ctx, "email", []byte("b"), EnqueueOptions{ID: "order-1"}); !errors.Is(err, ErrTaskExists) {
+		t.Fatalf("duplicate enqueue got %v, want ErrTaskExists", err)
+	}
+	if _, err := client.Enqueue(ctx, "email", nil, EnqueueOptions{ProcessIn: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := client.Stats(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Pending != 1 || stats.Scheduled != 1 {
+		t.Errorf("stats = %+v, want 1 pending and 1 scheduled", stats)
+	}
+}
+
+func TestServerProcessesTaskWithTraceContext(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	client, server := newTestQueue(t, ServerOptions{Concurrency: 2})
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	}))
+
+	received := make(chan trace.TraceID, 1)
+	server.Handle("email", func(ctx context.Context, task *Task) error {
+		received <- trace.SpanContextFromContext(ctx).TraceID()
+		return nil
+	})
+	server.Start(context.Background())
+	defer server.Stop()
+
+	if _, err := client.Enqueue(ctx, "email", []byte("hello"), EnqueueOptions{ProcessIn: 50 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-received:
+		if got != traceID {
+			t.Errorf("handler trace id = %s, want %s", got, traceID)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("task was not processed")
+	}
+	waitFor(t, func() bool {
+		stats, _ := client.Stats(context.Background(), "")
+		return stats == QueueStats{}
+	})
+}
+
+func TestServerRetriesThenKillsTask(t *testing.T) {
+	ctx := context.Background()
+	client, server := newTestQueue(t, ServerOptions{})
+
+	var attempts int32
+	server.Handle("flaky", func(ctx context.Context, task *Task) error {
+		atomic.AddInt32(&attempts, 1)
+		return errors.New("boom")
+	})
+	server.Start(ctx)
+	defer server.Stop()
+
+	id, err := client.Enqueue(ctx, "flaky", nil, EnqueueOptions{MaxRetry: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		stats, _ := client.Stats(ctx, "")
+		return stats.Dead == 1
+	})
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Errorf("attempts = %d, want 3", got)
+	}
+
+	dead, err := client.DeadTasks(ctx, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 || dead[0].ID != id || dead[0].Retried != 2 || dead[0].LastError != "boom" {
+		t.Fatalf("dead tasks = %+v", dead)
+	}
+	if _, err := client.Enqueue(ctx, "flaky", nil, EnqueueOptions{ID: id}); !errors.Is(err, ErrTaskExists) {
+		t.Errorf("enqueue of dead task got %v, want ErrTaskExists", err)
+	}
+
+	if err := client.RequeueDead(ctx, "", id); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return atomic.LoadInt32(&attempts) > 3 })
+}
+
+func TestServerRequeuesTaskCancelledByStop(t *testing.T) {
+	ctx := context.Background()
+	client, server := newTestQueue(t, ServerOptions{})
+
+	started := make(chan struct{})
+	server.Handle("report", func(ctx context.Context, task *Task) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	server.Start(ctx)
+	if _, err := client.Enqueue(ctx, "report", nil, EnqueueOptions{MaxRetry: 0}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-started:
+	case <-time.After(3 * time.Second):
+		t.Fatal("task was not processed")
+	}
+	server.Stop()
+
+	// 停止时中断的任务回到待处理列表，不计重试，也不会进入死信
+	stats, err := client.Stats(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Pending != 1 || stats.Dead != 0 || stats.Scheduled != 0 || stats.Active != 0 {
+		t.Errorf("stats = %+v, want 1 pending", stats)
+	}
+	task, _, err := server.dequeue(ctx)
+	if err != nil || task == nil || task.Retried != 0 {
+		t.Fatalf("requeued task = %+v, %v", task, err)
+	}
+}
+
+func TestServerKillsTaskAfterExpiredLeases(t *testing.T) {
+	ctx := context.Background()
+	client, server := newTestQueue(t, ServerOptions{LeaseDuration: 20 * time.Millisecond})
+	id, err := client.Enqueue(ctx, "crash", nil, EnqueueOptions{MaxRetry: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// worker 崩溃时不确认任务，租约过期后计为一次重试
+	for retried := 0; retried <= 1; retried++ {
+		task, _, err := server.dequeue(ctx)
+		if err != nil || task == nil || task.ID != id || task.Retried != retried {
+			t.Fatalf("dequeue %d got %+v, %v", retried, task, err)
+		}
+		time.Sleep(30 * time.Millisecond)
+	}
+	if task, _, err := server.dequeue(ctx); err != nil || task != nil {
+		t.Fatalf("dequeue after the last lease got %+v, %v", task, err)
+	}
+	dead, err := client.DeadTasks(ctx, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 || dead[0].ID != id || dead[0].LastError != "lease expired" {
+		t.Fatalf("dead tasks = %+v", dead)
+	}
+}
+
+func TestServerBackoff(t *testing.T) {
+	server := &Server{options: ServerOptions{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}}
+	for retried, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if got := server.backoff(retried); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", retried, got, want)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package taskqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultQueue is the queue used when none is given
+	DefaultQueue = "default"
+	// DefaultMaxRetry is the number of retries of a task when none is given
+	DefaultMaxRetry = 5
+	// DefaultKeyPrefix is prepended to every key of the queues
+	DefaultKeyPrefix = "taskqueue:"
+)
+
+var (
+	ErrTaskTypeRequired    = errors.New("taskqueue: task type is required")
+	ErrTaskHandlerRequired = errors.New("taskqueue: task handler is required")
+	ErrTaskExists          = errors.New("taskqueue: task already exists")
+	ErrTaskNotFound        = errors.New("taskqueue: task not found")
+	ErrLeaseLost           = errors.New("taskqueue: task lease lost")
+	// ErrSkipRetry can be wrapped in the error returned by a handler to send
+	// the task to the dead set without retrying it
+	ErrSkipRetry = errors.New("taskqueue: skip retry")
+)
+
+// Task is a unit of work taken by the handler registered for its type
+type Task struct {
+	// ID identifies the task in its queue, a task with the same id cannot be
+	// enqueued again until it has succeeded
+	ID string
+	// Type selects the handler of the task
+	Type string
+	// Payload is the opaque input of the handler
+	Payload []byte
+	// Queue is the queue the task was enqueued in
+	Queue string
+	// Retried is the number of failed attempts so far
+	Retried int
+	// MaxRetry is the number of retries before the task goes to the dead set
+	MaxRetry int
+	// Timeout cancels the context of an attempt, zero means no timeout
+	Timeout time.Duration
+	// LastError is the error of the last failed attempt
+	LastError string
+	// EnqueuedAt is when the task was enqueued
+	EnqueuedAt time.Time
+
+	headers string
+}
+
+// Handler processes a task, returning an error retries it
+type Handler func(ctx context.Context, task *Task) error
+
+// EnqueueOptions represents options of a single enqueued task
+type EnqueueOptions struct {
+	// ID deduplicates the task, defaults to a generated id
+	ID string
+	// Queue defaults to DefaultQueue
+	Queue string
+	// ProcessAt schedules the task, it takes precedence over ProcessIn
+	ProcessAt time.Time
+	// ProcessIn delays the task
+	ProcessIn time.Duration
+	// MaxRetry defaults to DefaultMaxRetry, a negative value disables retries
+	MaxRetry int
+	// Timeout cancels the context of each attempt, zero means no timeout
+	Timeout time.Duration
+}
+
+// QueueStats reports the number of tasks in each state of a queue
+type QueueStats struct {
+	Pending   int64
+	Scheduled int64
+	Active    int64
+	Dead      int64
+}
+
+// queueKeys derives the keys of a queue. They share a hash tag so the
+// scripts touching several of them also work under cluster mode.
+type queueKeys struct {
+	base string
+}
+
+func newQueueKeys(prefix, queue string) queueKeys {
+	return queueKeys{base: prefix + "{" + queue + "}"}
+}
+
+func (k queueKeys) pending() string   { return k.base + ":pending" }
+func (k queueKeys) scheduled() string { return k.base + ":scheduled" }
+func (k queueKeys) active() string    { return k.base + ":active" }
+func (k queueKeys) dead() string      { return k.base + ":dead" }
+func (k queueKeys) taskPrefix() string {
+	return k.base + ":task:"
+}
+func (k queueKeys) task(id string) string {
+	return k.taskPrefix() + id
+}
+
+// parseTask decodes the fields of a task hash
+func parseTask(queue string, fields map[string]string) (*Task, error) {
+	if len(fields) == 0 {
+		return nil, ErrTaskNotFound
+	}
+
+	task := &Task{
+		ID:        fields["id"],
+		Type:      fields["type"],
+		Payload:   []byte(fields["payload"]),
+		Queue:     queue,
+		LastError: fields["error"],
+		headers:   fields["headers"],
+	}
+	var err error
+	if task.Retried, err = strconv.Atoi(fields["retried"]); err != nil {
+		return nil, fmt.Errorf("taskqueue: invalid task %s: %w", task.ID, err)
+	}
+	if task.MaxRetry, err = strconv.Atoi(fields["max_retry"]); err != nil {
+		return nil, fmt.Errorf("taskqueue: invalid task %s: %w", task.ID, err)
+	}
+	timeout, err := strconv.ParseInt(fields["timeout"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("taskqueue: invalid task %s: %w", task.ID, err)
+	}
+	task.Timeout = time.Duration(timeout) * time.Millisecond
+	enqueuedAt, err := strconv.ParseInt(fields["enqueued_at"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("taskqueue: invalid task %s: %w", task.ID, err)
+	}
+	task.EnqueuedAt = time.UnixMilli(enqueuedAt)
+	return task, nil
+}
+
+// pairsToMap converts the flat reply of HGETALL returned by a script
+func pairsToMap(reply []interface{}) map[string]string {
+	fields := make(map[string]string, len(reply)/2)
+	for i := 0; i+1 < len(reply); i += 2 {
+		key, _ := reply[i].(string)
+		value, _ := reply[i+1].(string)
+		fields[key] = value
+	}
+	return fields
+}